@@ -0,0 +1,519 @@
+// Package analyzer fetches web pages and extracts insights from their content
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rabie/page-insight-tool/app/checker"
+	"github.com/rabie/page-insight-tool/app/config"
+	"github.com/rabie/page-insight-tool/app/helper"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	errUnsupportedScheme = errors.New("unsupported URL scheme")
+	errInvalidHostname   = errors.New("invalid hostname")
+	errResolveHost       = errors.New("failed to resolve host")
+	errPrivateNetwork    = errors.New("access to private network denied")
+)
+
+// LinkError describes why a page could not be analyzed
+type LinkError struct {
+	Link        string           `json:"link,omitempty"`
+	Status      int              `json:"status,omitempty"`
+	Kind        helper.ErrorKind `json:"kind,omitempty"`
+	Message     string           `json:"message"`
+	Explanation string           `json:"explanation,omitempty"`
+}
+
+// PageAnalysis holds the result of analyzing a web page
+type PageAnalysis struct {
+	ID                string                 `json:"id,omitempty"` // of the history record, once saved
+	URL               string                 `json:"url"`
+	FinalURL          string                 `json:"final_url,omitempty"`
+	Redirects         []helper.Hop           `json:"redirects,omitempty"`
+	RedirectIssues    []helper.RedirectIssue `json:"redirect_issues,omitempty"`
+	Title             string                 `json:"title"`
+	HTMLVersion       string                 `json:"html_version"`
+	HeadingsCount     map[string]int         `json:"headings_count"`
+	InternalLinks     int                    `json:"internal_links"`
+	ExternalLinks     int                    `json:"external_links"`
+	InaccessibleLinks int                    `json:"inaccessible_links"`
+	MaxLinks          int                    `json:"max_links,omitempty"` // links checked at most
+	LinkClasses       map[LinkClass]int      `json:"link_classes"`
+	HasLoginForm      bool                   `json:"has_login_form"`
+	Links             []LinkReport           `json:"links"`
+	Error             LinkError              `json:"error"`
+}
+
+// LinkReport describes the outcome of checking a single link found on the page
+type LinkReport struct {
+	URL            string                 `json:"url"`
+	Text           string                 `json:"text"`
+	Internal       bool                   `json:"internal"`
+	Accessible     bool                   `json:"accessible"`
+	Status         int                    `json:"status,omitempty"`
+	ErrorKind      helper.ErrorKind       `json:"error_kind,omitempty"`
+	Message        string                 `json:"message,omitempty"`
+	Explanation    string                 `json:"explanation,omitempty"`
+	Class          LinkClass              `json:"class"`
+	Method         string                 `json:"method,omitempty"`
+	Redirects      []helper.Hop           `json:"redirects,omitempty"`
+	RedirectIssues []helper.RedirectIssue `json:"redirect_issues,omitempty"`
+	Cached         bool                   `json:"cached,omitempty"`
+}
+
+// MarshalJSON omits the error object when the analysis succeeded
+func (p PageAnalysis) MarshalJSON() ([]byte, error) {
+	type analysis PageAnalysis
+	out := struct {
+		analysis
+		Error *LinkError `json:"error,omitempty"`
+	}{analysis: analysis(p)}
+	if p.Error.Message != "" {
+		out.Error = &p.Error
+	}
+	return json.Marshal(out)
+}
+
+// Refused reports whether the analysis ended without an answer from the page: the
+// URL was invalid, the host refused, or the request canceled
+func (p PageAnalysis) Refused() bool {
+	switch p.Error.Kind {
+	case helper.ErrorKindInvalidURL, helper.ErrorKindUnsupported, helper.ErrorKindBlocked, helper.ErrorKindPolicy, helper.ErrorKindCanceled:
+		return true
+	}
+	return false
+}
+
+// Options tune how an Analyzer follows the page and its links
+type Options struct {
+	PageTimeout  time.Duration // bounds fetching the page
+	LinkTimeout  time.Duration // bounds checking one link, both requests included
+	UserAgent    string
+	MaxLinks     int // links past this many on a page are not checked
+	MaxRedirects int // redirect chains longer than this are flagged as too long
+
+	// Resolver looks up target hosts, net.DefaultResolver when nil
+	Resolver helper.Resolver
+	// Deny reports the addresses that may not be reached, helper.IsPrivateIP when nil
+	Deny func(net.IP) bool
+	// Policy restricts the hosts of the analyzed pages, and of their links when
+	// PolicyForLinks is set. Every host is accepted when nil.
+	Policy         *helper.HostPolicy
+	PolicyForLinks bool
+	// Transport sends every request. When nil, a transport refusing to dial
+	// private addresses is used; tests may replace it to reach local servers.
+	Transport http.RoundTripper
+}
+
+// DefaultOptions returns the options used when none are configured, the defaults
+// of the configuration
+func DefaultOptions() Options {
+	return Options{
+		PageTimeout:  config.DefaultPageTimeout,
+		LinkTimeout:  config.DefaultLinkTimeout,
+		UserAgent:    config.DefaultUserAgent,
+		MaxLinks:     config.DefaultMaxLinks,
+		MaxRedirects: config.DefaultMaxRedirects,
+	}
+}
+
+// Analyzer runs page analyses. The link checks of every analysis share one checker pool,
+// which bounds the outbound requests of the whole process, and one result cache.
+type Analyzer struct {
+	pool      *checker.Pool
+	cache     *checker.Cache
+	opts      Options
+	resolver  helper.Resolver
+	deny      func(net.IP) bool
+	transport http.RoundTripper // fetches the page and follows its redirects
+	links     http.RoundTripper // checks the links
+
+	// live holds the analyzer built from the latest options; every analyzer
+	// it replaced shares it and hands new analyses over to it
+	live *atomic.Pointer[Analyzer]
+}
+
+// New returns an analyzer running its link checks on pool. Recent results are
+// taken from cache when it is not nil. Options left unset take their default.
+func New(pool *checker.Pool, cache *checker.Cache, opts Options) *Analyzer {
+	a := build(pool, cache, opts)
+	a.live = new(atomic.Pointer[Analyzer])
+	a.live.Store(a)
+	return a
+}
+
+// SetOptions replaces the options used by the analyses started from now on.
+// Analyses already running finish with the options they started with.
+func (a *Analyzer) SetOptions(opts Options) {
+	next := build(a.pool, a.cache, opts)
+	next.live = a.live
+	old := a.live.Swap(next)
+
+	// Connections kept alive by the replaced transports are not reused anymore
+	for _, transport := range []http.RoundTripper{old.transport, old.links} {
+		if t, ok := transport.(interface{ CloseIdleConnections() }); ok && transport != opts.Transport {
+			t.CloseIdleConnections()
+		}
+	}
+}
+
+// current returns the analyzer of the latest options
+func (a *Analyzer) current() *Analyzer {
+	return a.live.Load()
+}
+
+func build(pool *checker.Pool, cache *checker.Cache, opts Options) *Analyzer {
+	defaults := DefaultOptions()
+	if opts.PageTimeout <= 0 {
+		opts.PageTimeout = defaults.PageTimeout
+	}
+	if opts.LinkTimeout <= 0 {
+		opts.LinkTimeout = defaults.LinkTimeout
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = defaults.UserAgent
+	}
+	if opts.MaxLinks < 1 {
+		opts.MaxLinks = defaults.MaxLinks
+	}
+	if opts.MaxRedirects < 1 {
+		opts.MaxRedirects = defaults.MaxRedirects
+	}
+	a := &Analyzer{pool: pool, cache: cache, opts: opts, resolver: opts.Resolver, deny: opts.Deny,
+		transport: opts.Transport, links: opts.Transport}
+	if a.resolver == nil {
+		a.resolver = net.DefaultResolver
+	}
+	if a.deny == nil {
+		a.deny = helper.IsPrivateIP
+	}
+	if a.transport == nil {
+		a.transport = helper.NewSafeTransport(&helper.SafeDialer{Resolver: a.resolver, Deny: a.deny, Policy: opts.Policy})
+		a.links = a.transport
+		if !opts.PolicyForLinks {
+			a.links = helper.NewSafeTransport(&helper.SafeDialer{Resolver: a.resolver, Deny: a.deny})
+		}
+	}
+	return a
+}
+
+// AnalyzePage orchestrates the full analysis. Cancelling ctx stops every outstanding request.
+func (a *Analyzer) AnalyzePage(ctx context.Context, urlStr string) PageAnalysis {
+	return a.AnalyzePageWithProgress(ctx, urlStr, nil)
+}
+
+// AnalyzePageWithProgress runs the analysis and reports each phase and checked link to progress
+func (a *Analyzer) AnalyzePageWithProgress(ctx context.Context, urlStr string, progress ProgressFunc) (result PageAnalysis) {
+	a = a.current()
+	analysesStarted.Inc()
+	start := time.Now()
+	slog.DebugContext(ctx, "analysis started", "url", urlStr)
+	defer func() {
+		recordAnalysis(result)
+		slog.DebugContext(ctx, "analysis finished",
+			"url", urlStr,
+			"duration", time.Since(start),
+			"error_kind", result.Error.Kind,
+			"error", result.Error.Message,
+			"links", len(result.Links),
+			"broken_links", result.InaccessibleLinks,
+		)
+	}()
+
+	result = PageAnalysis{
+		URL:           urlStr,
+		MaxLinks:      a.opts.MaxLinks,
+		HeadingsCount: make(map[string]int),
+		LinkClasses:   make(map[LinkClass]int),
+	}
+
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		result.Error = LinkError{Kind: helper.ErrorKindInvalidURL, Message: "Invalid URL"}
+		return result
+	}
+
+	if err := a.validateURL(ctx, parsedURL); err != nil {
+		kind := helper.ErrorKindInvalidURL
+		switch {
+		case errors.Is(err, errPrivateNetwork):
+			kind = helper.ErrorKindBlocked
+			ssrfRejections.Inc(string(kind))
+		case errors.Is(err, helper.ErrHostPolicy):
+			kind = helper.ErrorKindPolicy
+			ssrfRejections.Inc(string(kind))
+		case errors.Is(err, errResolveHost):
+			kind = helper.ErrorKindDNS
+		case ctx.Err() != nil:
+			kind = helper.ClassifyError(err)
+		}
+		result.Error = LinkError{Kind: kind, Message: err.Error(), Explanation: helper.ExplainErrorKind(kind)}
+		return result
+	}
+
+	progress.report(Progress{Phase: PhaseFetch})
+	fetchStart := time.Now()
+	doc, trace, linkError := a.fetchPage(ctx, parsedURL.String())
+	fetchKind, fetchStatus := helper.ErrorKind(""), http.StatusOK
+	if linkError != nil {
+		fetchKind, fetchStatus = linkError.Kind, linkError.Status
+	}
+	pageFetchSeconds.Observe(time.Since(fetchStart).Seconds(), string(fetchKind))
+	slog.DebugContext(ctx, "page fetched",
+		"url", parsedURL.String(),
+		"status", fetchStatus,
+		"error_kind", fetchKind,
+		"redirects", len(trace.Hops),
+		"duration", time.Since(fetchStart),
+	)
+	result.Redirects = trace.Hops
+	result.RedirectIssues = trace.Issues
+	if linkError != nil {
+		result.Error = *linkError
+		return result
+	}
+
+	// Relative links and internal hosts are those of the page actually served
+	if trace.Redirected() {
+		result.FinalURL = trace.Hops[len(trace.Hops)-1].URL
+		if final, err := url.Parse(result.FinalURL); err == nil {
+			parsedURL = final
+		}
+	}
+
+	progress.report(Progress{Phase: PhaseParse})
+	result.Title = extractTitle(doc)
+	result.HTMLVersion = detectHTMLVersion(doc)
+	result.HeadingsCount = countHeadings(doc)
+	result.Links = a.countLinks(ctx, doc, parsedURL, progress)
+	for _, link := range result.Links {
+		result.LinkClasses[link.Class]++
+		switch {
+		case link.Class.Broken():
+			result.InaccessibleLinks++
+		case link.Internal:
+			result.InternalLinks++
+		default:
+			result.ExternalLinks++
+		}
+	}
+	result.HasLoginForm = detectLoginForm(doc)
+
+	// Links left unchecked make the result incomplete
+	if err := ctx.Err(); err != nil {
+		result.Error = LinkError{
+			Kind:    helper.ClassifyError(err),
+			Message: "analysis interrupted: " + err.Error(),
+		}
+	}
+
+	return result
+}
+
+// validateURL ensures the URL is safe to access and accepted by the host policy. The
+// transport checks the addresses and the policy again when connecting, to every redirect
+// hop too; this early check gives a clear error.
+func (a *Analyzer) validateURL(ctx context.Context, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errUnsupportedScheme
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return errInvalidHostname
+	}
+
+	addrs, err := a.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return errResolveHost
+	}
+
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		if a.deny(addr.IP) {
+			return errPrivateNetwork
+		}
+		ips[i] = addr.IP
+	}
+	return a.opts.Policy.Check(host, ips)
+}
+
+// fetchPage retrieves and parses the remote page, recording the redirects it went through
+func (a *Analyzer) fetchPage(ctx context.Context, urlStr string) (*goquery.Document, *helper.RedirectTrace, *LinkError) {
+	linkError := &LinkError{
+		Link: urlStr,
+	}
+	trace := helper.NewRedirectTrace(a.opts.MaxRedirects)
+	ctx, cancel := context.WithTimeout(ctx, a.opts.PageTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+	if err != nil {
+		linkError.Kind = helper.ErrorKindInvalidURL
+		linkError.Message = err.Error()
+		return nil, trace, linkError
+	}
+	req.Header.Set("User-Agent", a.opts.UserAgent)
+
+	resp, err := trace.Client(a.transport).Do(req)
+	if err != nil {
+		linkError.Kind = helper.ClassifyError(err)
+		linkError.Message = err.Error()
+		linkError.Explanation = helper.ExplainErrorKind(linkError.Kind)
+		return nil, trace, linkError
+	}
+	defer resp.Body.Close()
+	trace.Finish(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		linkError.Kind = helper.ClassifyStatus(resp.StatusCode)
+		linkError.Message = http.StatusText(resp.StatusCode)
+		linkError.Status = resp.StatusCode
+		linkError.Explanation = helper.GetExplanation(resp.StatusCode)
+		return nil, trace, linkError
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		linkError.Kind = helper.ErrorKindParse
+		linkError.Message = err.Error()
+		return nil, trace, linkError
+	}
+	return doc, trace, nil
+}
+
+// extractTitle gets the page <title>
+func extractTitle(doc *goquery.Document) string {
+	return strings.TrimSpace(doc.Find("title").First().Text())
+}
+
+// detectHTMLVersion infers the HTML version
+func detectHTMLVersion(doc *goquery.Document) string {
+	if doc.Find("article, aside, footer, header, main, nav, section").Length() > 0 {
+		return "HTML5"
+	}
+	if doc.Find("html[xmlns]").Length() > 0 {
+		return "XHTML"
+	}
+	return "HTML5"
+}
+
+// countHeadings returns a map of H1–H6 counts
+func countHeadings(doc *goquery.Document) map[string]int {
+	counts := make(map[string]int)
+	for i := 1; i <= 6; i++ {
+		h := fmt.Sprintf("h%d", i)
+		counts[h] = doc.Find(h).Length()
+	}
+	return counts
+}
+
+// countLinks checks the page links and reports each one as internal/external and accessible or not
+func (a *Analyzer) countLinks(ctx context.Context, doc *goquery.Document, base *url.URL, progress ProgressFunc) []LinkReport {
+	var links []*url.URL
+	var texts []string
+
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		raw, ok := s.Attr("href")
+		if !ok {
+			return
+		}
+
+		ref, err := url.Parse(raw)
+		if err != nil {
+			return
+		}
+
+		if !ref.IsAbs() {
+			ref = base.ResolveReference(ref)
+		}
+		links = append(links, ref)
+		texts = append(texts, anchorText(s))
+	})
+
+	if len(links) > a.opts.MaxLinks {
+		links = links[:a.opts.MaxLinks]
+	}
+
+	status := Progress{Phase: PhaseLinks, LinksDiscovered: len(links)}
+	progress.report(status)
+
+	reports := make([]LinkReport, len(links))
+	a.checkLinksConcurrently(ctx, links, func(i int, res linkResult) {
+		reports[i] = LinkReport{
+			URL:            res.link.String(),
+			Text:           texts[i],
+			Internal:       res.link.Hostname() == base.Hostname(),
+			Accessible:     res.isAccessible,
+			Status:         res.status,
+			ErrorKind:      res.kind,
+			Message:        res.message,
+			Class:          res.class(),
+			Method:         res.method,
+			Cached:         res.cached,
+			Redirects:      res.redirects,
+			RedirectIssues: res.redirectIssues,
+		}
+		if res.status != 0 && res.kind != helper.ErrorKindBotBlocked {
+			reports[i].Explanation = helper.GetExplanation(res.status)
+		} else {
+			reports[i].Explanation = helper.ExplainErrorKind(res.kind)
+		}
+
+		status.LinksChecked++
+		if reports[i].Class.Broken() {
+			status.LinksBroken++
+		}
+		status.Link = &reports[i]
+		progress.report(status)
+	})
+	return reports
+}
+
+// anchorText returns the visible text of a link, falling back to its labels
+func anchorText(s *goquery.Selection) string {
+	if text := strings.Join(strings.Fields(s.Text()), " "); text != "" {
+		return text
+	}
+	for _, attr := range []string{"aria-label", "title"} {
+		if v, ok := s.Attr(attr); ok && strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	if alt, ok := s.Find("img[alt]").First().Attr("alt"); ok {
+		return strings.TrimSpace(alt)
+	}
+	return ""
+}
+
+// detectLoginForm finds login forms based on common patterns
+func detectLoginForm(doc *goquery.Document) bool {
+	found := false
+	doc.Find("form").EachWithBreak(func(i int, form *goquery.Selection) bool {
+		hasPwd := form.Find("input[type='password']").Length() > 0
+		hasUser := form.Find("input[type='text'], input[type='email'], input[name*='user'], input[id*='user']").Length() > 0
+		formText := strings.ToLower(form.Text())
+
+		if hasPwd && (hasUser || strings.Contains(formText, "login") || strings.Contains(formText, "sign in")) {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
+}
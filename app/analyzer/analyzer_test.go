@@ -0,0 +1,401 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/rabie/page-insight-tool/app/checker"
+	"github.com/rabie/page-insight-tool/app/helper"
+	"github.com/rabie/page-insight-tool/app/logging"
+)
+
+func newTestAnalyzer(t *testing.T) *Analyzer {
+	pool := checker.NewPool(4)
+	t.Cleanup(pool.Close)
+	// Link checks in tests reach httptest servers on loopback
+	opts := DefaultOptions()
+	opts.Transport = http.DefaultTransport
+	return New(pool, nil, opts)
+}
+
+func TestAnalyzePage_InvalidURL(t *testing.T) {
+	analysis := newTestAnalyzer(t).AnalyzePage(context.Background(), "invalid-url")
+
+	if analysis.Error.Message == "" {
+		t.Error("expected error for invalid URL, got none")
+	}
+}
+
+func TestAnalyzePage_PrivateIP(t *testing.T) {
+	analysis := newTestAnalyzer(t).AnalyzePage(context.Background(), "http://192.168.1.1")
+
+	if analysis.Error.Message == "" {
+		t.Error("expected error for private IP, got none")
+	}
+
+	if !strings.Contains(analysis.Error.Message, "access to private network denied") {
+		t.Errorf("expected private network error, got: %s", analysis.Error.Message)
+	}
+}
+
+func TestValidateURL_ValidURL(t *testing.T) {
+	u, _ := url.Parse("https://example.com")
+	err := newTestAnalyzer(t).validateURL(context.Background(), u)
+
+	if err != nil {
+		t.Errorf("expected no error for valid URL, got: %v", err)
+	}
+}
+
+func TestValidateURL_InvalidScheme(t *testing.T) {
+	u, _ := url.Parse("ftp://example.com")
+	err := newTestAnalyzer(t).validateURL(context.Background(), u)
+
+	if err == nil {
+		t.Error("expected error for invalid scheme, got none")
+	}
+
+	if !strings.Contains(err.Error(), "unsupported URL scheme") {
+		t.Errorf("expected scheme error, got: %s", err.Error())
+	}
+}
+
+func TestCountLinks_Report(t *testing.T) {
+	html := `<html><body>
+<a href="mailto:test@example.com">  Mail
+   us </a>
+<a href="tel:+1234567890" title="Call us"></a>
+<a href="javascript:void(0)"><img src="x.png" alt="Icon"></a>
+</body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/page")
+
+	reports := newTestAnalyzer(t).countLinks(context.Background(), doc, base, nil)
+	if len(reports) != 3 {
+		t.Fatalf("expected 3 link reports, got %d", len(reports))
+	}
+
+	wantTexts := []string{"Mail us", "Call us", "Icon"}
+	for i, report := range reports {
+		if report.Text != wantTexts[i] {
+			t.Errorf("link %d: expected text %q, got %q", i, wantTexts[i], report.Text)
+		}
+		if report.Accessible {
+			t.Errorf("link %d: expected %s to be inaccessible", i, report.URL)
+		}
+		if report.ErrorKind != helper.ErrorKindUnsupported {
+			t.Errorf("link %d: expected error kind %q, got %q", i, helper.ErrorKindUnsupported, report.ErrorKind)
+		}
+		if report.Explanation == "" {
+			t.Errorf("link %d: expected an explanation", i)
+		}
+	}
+	if reports[0].URL != "mailto:test@example.com" {
+		t.Errorf("expected links in document order, got %s first", reports[0].URL)
+	}
+}
+
+func TestPageAnalysis_MarshalJSON(t *testing.T) {
+	ok, err := json.Marshal(PageAnalysis{URL: "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(ok), `"error"`) {
+		t.Errorf("expected no error object for a successful analysis, got %s", ok)
+	}
+
+	failed, err := json.Marshal(PageAnalysis{Error: LinkError{Message: "Not Found", Status: 404}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(failed), `"error":{"status":404,"message":"Not Found"}`) {
+		t.Errorf("expected error object, got %s", failed)
+	}
+}
+
+func TestCountLinks_Progress(t *testing.T) {
+	html := `<html><body><a href="mailto:a@example.com">A</a><a href="tel:+123">B</a></body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com")
+
+	var updates []Progress
+	newTestAnalyzer(t).countLinks(context.Background(), doc, base, func(p Progress) {
+		updates = append(updates, p)
+	})
+
+	if len(updates) != 3 {
+		t.Fatalf("expected 3 progress updates, got %d", len(updates))
+	}
+	if updates[0].Phase != PhaseLinks || updates[0].LinksDiscovered != 2 || updates[0].Link != nil {
+		t.Errorf("unexpected first update: %+v", updates[0])
+	}
+	last := updates[2]
+	if last.LinksChecked != 2 || last.LinksBroken != 2 || last.Link == nil {
+		t.Errorf("unexpected last update: %+v", last)
+	}
+}
+
+func TestFetchPage_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, linkError := newTestAnalyzer(t).fetchPage(ctx, "https://example.com")
+	if linkError == nil {
+		t.Fatal("expected error for canceled context, got none")
+	}
+	if linkError.Kind != helper.ErrorKindCanceled {
+		t.Errorf("expected error kind %q, got %q", helper.ErrorKindCanceled, linkError.Kind)
+	}
+}
+
+func TestFetchPage_Redirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new/", http.StatusMovedPermanently)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			w.Write([]byte(`<html><head><title>New</title></head></html>`))
+		}
+	}))
+	defer server.Close()
+
+	a := newTestAnalyzer(t)
+	doc, trace, linkError := a.fetchPage(context.Background(), server.URL+"/old")
+	if linkError != nil {
+		t.Fatalf("unexpected error %+v", linkError)
+	}
+	if extractTitle(doc) != "New" {
+		t.Errorf("expected the redirect target to be parsed")
+	}
+	if len(trace.Hops) != 2 || trace.Hops[0].Status != 301 || trace.Hops[1].URL != server.URL+"/new/" {
+		t.Errorf("unexpected redirect chain %+v", trace.Hops)
+	}
+
+	_, trace, linkError = a.fetchPage(context.Background(), server.URL+"/loop")
+	if linkError == nil || linkError.Kind != helper.ErrorKindRedirect {
+		t.Fatalf("expected a redirect error, got %+v", linkError)
+	}
+	if len(trace.Issues) != 1 || trace.Issues[0] != helper.RedirectLoop {
+		t.Errorf("expected a loop to be flagged, got %v", trace.Issues)
+	}
+}
+
+// rebindingResolver answers a public address to the first lookup of a host and
+// loopback to the next ones, like a DNS rebinding attack
+type rebindingResolver struct {
+	mu      sync.Mutex
+	lookups map[string]int
+}
+
+func (r *rebindingResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lookups[host]++
+	if r.lookups[host] == 1 {
+		return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+	}
+	return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, nil
+}
+
+func TestAnalyzePage_DNSRebinding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no request to reach the loopback server")
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	pool := checker.NewPool(1)
+	defer pool.Close()
+	a := New(pool, nil, Options{Resolver: &rebindingResolver{lookups: map[string]int{}}})
+
+	analysis := a.AnalyzePage(context.Background(), "http://rebind.test:"+port)
+	if analysis.Error.Kind != helper.ErrorKindBlocked {
+		t.Errorf("expected the connection to be blocked, got %+v", analysis.Error)
+	}
+}
+
+func TestCheckLink_PrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no request to reach the loopback server")
+	}))
+	defer server.Close()
+
+	pool := checker.NewPool(1)
+	defer pool.Close()
+	a := New(pool, nil, DefaultOptions())
+
+	link, _ := url.Parse(server.URL)
+	res := a.checkLink(context.Background(), link)
+	if res.isAccessible || res.kind != helper.ErrorKindBlocked || res.class() != ClassNotChecked {
+		t.Errorf("expected the link to be blocked, got %+v", res)
+	}
+}
+
+func TestValidateURL_DeniedNetwork(t *testing.T) {
+	pool := checker.NewPool(1)
+	defer pool.Close()
+	denyList, err := helper.NewIPDenyList([]string{"93.184.216.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := &rebindingResolver{lookups: map[string]int{}}
+	a := New(pool, nil, Options{Resolver: resolver, Deny: denyList.Denies})
+
+	analysis := a.AnalyzePage(context.Background(), "http://denied.test")
+	if analysis.Error.Kind != helper.ErrorKindBlocked {
+		t.Errorf("expected the extra denied network to be blocked, got %+v", analysis.Error)
+	}
+}
+
+func TestAnalyzePage_HostPolicy(t *testing.T) {
+	pool := checker.NewPool(1)
+	defer pool.Close()
+	policy, err := helper.NewHostPolicy([]string{"*.example.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := &rebindingResolver{lookups: map[string]int{}}
+	a := New(pool, nil, Options{Resolver: resolver, Policy: policy})
+
+	analysis := a.AnalyzePage(context.Background(), "http://elsewhere.test")
+	if analysis.Error.Kind != helper.ErrorKindPolicy || analysis.Error.Explanation == "" {
+		t.Errorf("expected the host to be refused by policy, got %+v", analysis.Error)
+	}
+	if !strings.Contains(analysis.Error.Message, "elsewhere.test is not in the allowed hosts") {
+		t.Errorf("expected the refused host in the message, got %q", analysis.Error.Message)
+	}
+}
+
+func TestCheckLink_HostPolicy(t *testing.T) {
+	pool := checker.NewPool(1)
+	defer pool.Close()
+	policy, _ := helper.NewHostPolicy(nil, []string{"denied.test"})
+	resolver := &rebindingResolver{lookups: map[string]int{}}
+	link, _ := url.Parse("http://denied.test/page")
+
+	a := New(pool, nil, Options{Resolver: resolver, Policy: policy, PolicyForLinks: true})
+	res := a.checkLink(context.Background(), link)
+	if res.kind != helper.ErrorKindPolicy || res.class() != ClassNotChecked {
+		t.Errorf("expected the link to be refused by policy, got %+v", res)
+	}
+
+	// Without PolicyForLinks the link is checked; it then reaches the rebound loopback address
+	a = New(pool, nil, Options{Resolver: resolver, Policy: policy})
+	if res := a.checkLink(context.Background(), link); res.kind == helper.ErrorKindPolicy {
+		t.Errorf("expected links to be checked when the policy only applies to pages, got %+v", res)
+	}
+}
+
+func TestAnalyzePage_Options(t *testing.T) {
+	var mu sync.Mutex
+	agents := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents[r.UserAgent()] = true
+		mu.Unlock()
+		w.Write([]byte(`<html><body><a href="/a">A</a><a href="/b">B</a><a href="/c">C</a></body></html>`))
+	}))
+	defer server.Close()
+
+	pool := checker.NewPool(2)
+	defer pool.Close()
+	a := New(pool, nil, Options{
+		UserAgent: "Crawler/2.0",
+		MaxLinks:  2,
+		Deny:      func(net.IP) bool { return false }, // the test server listens on loopback
+		Transport: http.DefaultTransport,
+	})
+
+	analysis := a.AnalyzePage(context.Background(), server.URL)
+	if len(analysis.Links) != 2 {
+		t.Errorf("expected links past MaxLinks to be skipped, got %d", len(analysis.Links))
+	}
+	if len(agents) != 1 || !agents["Crawler/2.0"] {
+		t.Errorf("expected every request to carry the configured user agent, got %v", agents)
+	}
+}
+
+func TestAnalyzer_SetOptions(t *testing.T) {
+	agents := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents <- r.UserAgent()
+		w.Write([]byte(`<html><head><title>T</title></head></html>`))
+	}))
+	defer server.Close()
+
+	pool := checker.NewPool(1)
+	defer pool.Close()
+	opts := Options{
+		UserAgent: "Before/1.0",
+		Deny:      func(net.IP) bool { return false }, // the test server listens on loopback
+		Transport: http.DefaultTransport,
+	}
+	a := New(pool, nil, opts)
+
+	opts.UserAgent = "After/2.0"
+	a.SetOptions(opts)
+	if analysis := a.AnalyzePage(context.Background(), server.URL); analysis.Error.Message != "" {
+		t.Fatalf("unexpected error %+v", analysis.Error)
+	}
+	if agent := <-agents; agent != "After/2.0" {
+		t.Errorf("expected the new options to apply to the next analysis, got %q", agent)
+	}
+}
+
+func TestAnalyzePage_DebugTraces(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(`<html><body><a href="/a">A</a></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&buf, slog.LevelDebug))
+	defer slog.SetDefault(previous)
+
+	pool := checker.NewPool(1)
+	defer pool.Close()
+	a := New(pool, nil, Options{
+		Deny:      func(net.IP) bool { return false }, // the test server listens on loopback
+		Transport: http.DefaultTransport,
+	})
+	a.AnalyzePage(logging.WithRequestID(context.Background(), "req-7"), server.URL)
+
+	messages := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		if record["request_id"] != "req-7" {
+			t.Errorf("expected every line to carry the request ID, got %v", record)
+		}
+		messages[record["msg"].(string)] = true
+	}
+	for _, want := range []string{"analysis started", "page fetched", "link checked", "analysis finished"} {
+		if !messages[want] {
+			t.Errorf("expected a %q line, got %v", want, messages)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// DefaultBatchConcurrency is the number of pages analyzed in parallel by a batch
+const DefaultBatchConcurrency = 4
+
+// Input formats accepted by ParseURLList
+const (
+	FormatText = "text"
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// BatchReport aggregates the analyses of several pages
+type BatchReport struct {
+	Results []PageAnalysis `json:"results"`
+	Summary BatchSummary   `json:"summary"`
+}
+
+// BatchSummary holds the totals over every page of a batch
+type BatchSummary struct {
+	URLs          int `json:"urls"`
+	Succeeded     int `json:"succeeded"`
+	Failed        int `json:"failed"`
+	BrokenLinks   int `json:"broken_links"`
+	MissingTitles int `json:"missing_titles"`
+	LoginForms    int `json:"login_forms"`
+}
+
+// AnalyzeBatch analyzes every URL with at most concurrency pages in flight,
+// keeping the results in the order of the input
+func (a *Analyzer) AnalyzeBatch(ctx context.Context, urls []string, concurrency int) BatchReport {
+	if concurrency < 1 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	results := make([]PageAnalysis, len(urls))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < concurrency && i < len(urls); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results[idx] = a.AnalyzePage(ctx, urls[idx])
+			}
+		}()
+	}
+
+	for i := range urls {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return BatchReport{
+		Results: results,
+		Summary: summarize(results),
+	}
+}
+
+func summarize(results []PageAnalysis) BatchSummary {
+	summary := BatchSummary{URLs: len(results)}
+	for _, result := range results {
+		if result.Error.Message != "" {
+			summary.Failed++
+			continue
+		}
+		summary.Succeeded++
+		summary.BrokenLinks += result.InaccessibleLinks
+		if result.Title == "" {
+			summary.MissingTitles++
+		}
+		if result.HasLoginForm {
+			summary.LoginForms++
+		}
+	}
+	return summary
+}
+
+// ParseURLList reads a list of URLs given as newline separated text, CSV or a JSON array.
+// Blank lines, # comments and duplicates are skipped.
+func ParseURLList(r io.Reader, format string) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []string
+	switch format {
+	case FormatText:
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); !strings.HasPrefix(line, "#") {
+				raw = append(raw, line)
+			}
+		}
+	case FormatCSV:
+		raw, err = parseCSV(data)
+	case FormatJSON:
+		raw, err = parseJSON(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	urls := make([]string, 0, len(raw))
+	for _, u := range raw {
+		u = strings.TrimSpace(u)
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+// parseCSV reads the "url" column, or the first column when there is no such header
+func parseCSV(data []byte) ([]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	column := 0
+	for i, field := range records[0] {
+		if strings.EqualFold(strings.TrimSpace(field), "url") {
+			column = i
+			records = records[1:]
+			break
+		}
+	}
+
+	var urls []string
+	for _, record := range records {
+		if column < len(record) {
+			urls = append(urls, record[column])
+		}
+	}
+	return urls, nil
+}
+
+// parseJSON accepts either ["url", ...] or {"urls": ["url", ...]}
+func parseJSON(data []byte) ([]string, error) {
+	var urls []string
+	if err := json.Unmarshal(data, &urls); err == nil {
+		return urls, nil
+	}
+
+	var body struct {
+		URLs []string `json:"urls"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("invalid JSON: expected an array of URLs or an object with a \"urls\" array")
+	}
+	return body.URLs, nil
+}
@@ -0,0 +1,100 @@
+package analyzer
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseURLList(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   []string
+	}{
+		{
+			name:   "text",
+			format: FormatText,
+			input:  "https://a.example\n\n# comment\n  https://b.example  \r\nhttps://a.example\n",
+			want:   []string{"https://a.example", "https://b.example"},
+		},
+		{
+			name:   "csv with header",
+			format: FormatCSV,
+			input:  "name,URL\nhome,https://a.example\nblog,https://b.example\n",
+			want:   []string{"https://a.example", "https://b.example"},
+		},
+		{
+			name:   "csv without header",
+			format: FormatCSV,
+			input:  "https://a.example,home\nhttps://b.example\n",
+			want:   []string{"https://a.example", "https://b.example"},
+		},
+		{
+			name:   "json array",
+			format: FormatJSON,
+			input:  `["https://a.example", "https://b.example"]`,
+			want:   []string{"https://a.example", "https://b.example"},
+		},
+		{
+			name:   "json object",
+			format: FormatJSON,
+			input:  `{"urls": ["https://a.example"]}`,
+			want:   []string{"https://a.example"},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseURLList(strings.NewReader(tt.input), tt.format)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestParseURLList_Invalid(t *testing.T) {
+	if _, err := ParseURLList(strings.NewReader(`{"url": 1`), FormatJSON); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+	if _, err := ParseURLList(strings.NewReader("a"), "xml"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestAnalyzeBatch(t *testing.T) {
+	urls := []string{"http://192.168.1.1", "invalid-url", "ftp://example.com"}
+
+	report := newTestAnalyzer(t).AnalyzeBatch(context.Background(), urls, 2)
+
+	if len(report.Results) != len(urls) {
+		t.Fatalf("expected %d results, got %d", len(urls), len(report.Results))
+	}
+	for i, result := range report.Results {
+		if result.URL != urls[i] {
+			t.Errorf("expected result %d for %s, got %s", i, urls[i], result.URL)
+		}
+	}
+	if report.Summary.URLs != 3 || report.Summary.Failed != 3 || report.Summary.Succeeded != 0 {
+		t.Errorf("unexpected summary: %+v", report.Summary)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	results := []PageAnalysis{
+		{Title: "Home", InaccessibleLinks: 2, HasLoginForm: true},
+		{InaccessibleLinks: 1},
+		{Error: LinkError{Message: "Not Found"}},
+	}
+
+	got := summarize(results)
+	want := BatchSummary{URLs: 3, Succeeded: 2, Failed: 1, BrokenLinks: 3, MissingTitles: 1, LoginForms: 1}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
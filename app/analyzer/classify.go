@@ -0,0 +1,106 @@
+package analyzer
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/rabie/page-insight-tool/app/helper"
+)
+
+// LinkClass is the category a checked link falls into
+type LinkClass string
+
+const (
+	ClassOK           LinkClass = "ok"
+	ClassRedirected   LinkClass = "redirected"
+	ClassBotProtected LinkClass = "bot_protected"
+	ClassClientError  LinkClass = "client_error"
+	ClassServerError  LinkClass = "server_error"
+	ClassNetworkError LinkClass = "network_error"
+	ClassNotChecked   LinkClass = "not_checked"
+)
+
+// AllLinkClasses lists the link classes in report order
+var AllLinkClasses = []LinkClass{
+	ClassOK,
+	ClassRedirected,
+	ClassBotProtected,
+	ClassClientError,
+	ClassServerError,
+	ClassNetworkError,
+	ClassNotChecked,
+}
+
+// Broken reports whether links of the class count as inaccessible. Links behind
+// bot protection answer browsers and are reported on their own instead.
+func (c LinkClass) Broken() bool {
+	return c != ClassOK && c != ClassRedirected && c != ClassBotProtected
+}
+
+// class sorts the outcome of a link check into its LinkClass
+func (r linkResult) class() LinkClass {
+	switch {
+	case r.kind == helper.ErrorKindBotBlocked:
+		return ClassBotProtected
+	case r.isAccessible && r.redirected:
+		return ClassRedirected
+	case r.isAccessible:
+		return ClassOK
+	case r.status >= 500:
+		return ClassServerError
+	case r.status >= 400:
+		return ClassClientError
+	case r.kind == helper.ErrorKindUnsupported, r.kind == helper.ErrorKindInvalidURL,
+		r.kind == helper.ErrorKindCanceled, r.kind == helper.ErrorKindBlocked, r.kind == helper.ErrorKindPolicy:
+		return ClassNotChecked
+	default:
+		return ClassNetworkError
+	}
+}
+
+// headRejected reports whether a HEAD answer may only mean the server does not
+// support HEAD, in which case the link is checked again with GET
+func headRejected(status int) bool {
+	return status == http.StatusMethodNotAllowed ||
+		status == http.StatusForbidden ||
+		status == http.StatusNotImplemented
+}
+
+// botChallengeHeaders are set by bot protection services on the challenges they serve
+var botChallengeHeaders = []string{"Cf-Mitigated", "X-Datadome", "X-Amzn-Waf-Action", "X-Sucuri-Block"}
+
+// botChallengeMarkers are found in the body of challenge pages
+var botChallengeMarkers = [][]byte{
+	[]byte("captcha"),
+	[]byte("challenge-platform"),
+	[]byte("just a moment..."),
+	[]byte("attention required"),
+	[]byte("access denied | "),
+}
+
+// botProtected reports whether an error answer comes from a bot protection service
+// rather than from the link target. body holds the start of the answer, if read.
+func botProtected(res *http.Response, body []byte) bool {
+	// LinkedIn and a few others answer crawlers with this non-standard status
+	if res.StatusCode == 999 {
+		return true
+	}
+	if res.StatusCode != http.StatusForbidden &&
+		res.StatusCode != http.StatusTooManyRequests &&
+		res.StatusCode != http.StatusServiceUnavailable {
+		return false
+	}
+
+	for _, h := range botChallengeHeaders {
+		if res.Header.Get(h) != "" {
+			return true
+		}
+	}
+	body = bytes.ToLower(body)
+	for _, marker := range botChallengeMarkers {
+		if bytes.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package analyzer
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rabie/page-insight-tool/app/helper"
+)
+
+func TestLinkResult_Class(t *testing.T) {
+	tests := []struct {
+		name string
+		res  linkResult
+		want LinkClass
+	}{
+		{"ok", linkResult{isAccessible: true, status: 200}, ClassOK},
+		{"redirected", linkResult{isAccessible: true, status: 200, redirected: true}, ClassRedirected},
+		{"bot protection", linkResult{status: 403, kind: helper.ErrorKindBotBlocked}, ClassBotProtected},
+		{"client error", linkResult{status: 404, kind: helper.ErrorKindClientError}, ClassClientError},
+		{"server error", linkResult{status: 502, kind: helper.ErrorKindServerError}, ClassServerError},
+		{"dns", linkResult{kind: helper.ErrorKindDNS}, ClassNetworkError},
+		{"timeout", linkResult{kind: helper.ErrorKindTimeout}, ClassNetworkError},
+		{"tls", linkResult{kind: helper.ErrorKindTLS}, ClassNetworkError},
+		{"unsupported", linkResult{kind: helper.ErrorKindUnsupported}, ClassNotChecked},
+		{"canceled", linkResult{kind: helper.ErrorKindCanceled}, ClassNotChecked},
+	}
+
+	for _, tt := range tests {
+		if got := tt.res.class(); got != tt.want {
+			t.Errorf("%s: expected class %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestLinkClass_Broken(t *testing.T) {
+	for _, class := range AllLinkClasses {
+		want := class != ClassOK && class != ClassRedirected && class != ClassBotProtected
+		if class.Broken() != want {
+			t.Errorf("class %q: expected Broken() to be %v", class, want)
+		}
+	}
+}
+
+func TestBotProtected(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		body   string
+		want   bool
+	}{
+		{"cloudflare challenge header", 403, http.Header{"Cf-Mitigated": {"challenge"}}, "", true},
+		{"captcha page", 403, http.Header{}, "<html>Please solve the CAPTCHA</html>", true},
+		{"non-standard 999", 999, http.Header{}, "", true},
+		{"plain forbidden", 403, http.Header{}, "<html>Forbidden</html>", false},
+		{"not found with captcha text", 404, http.Header{}, "captcha", false},
+	}
+
+	for _, tt := range tests {
+		res := &http.Response{StatusCode: tt.status, Header: tt.header}
+		if got := botProtected(res, []byte(tt.body)); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
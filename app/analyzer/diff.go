@@ -0,0 +1,156 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Diff reports what changed between two analyses, typically of the same page
+// before and after a release, or of the same page on two deployments
+type Diff struct {
+	Before      DiffSide        `json:"before"`
+	After       DiffSide        `json:"after"`
+	Changed     bool            `json:"changed"`
+	Regressed   bool            `json:"regressed"` // links broke, headings were lost or the page now fails
+	Error       *ErrorChange    `json:"error,omitempty"`
+	Title       *TextChange     `json:"title,omitempty"`
+	HTMLVersion *TextChange     `json:"html_version,omitempty"`
+	Headings    []HeadingChange `json:"headings,omitempty"`
+	NewlyBroken []LinkReport    `json:"newly_broken,omitempty"`
+	NewlyFixed  []LinkReport    `json:"newly_fixed,omitempty"`
+	LoginForm   *FlagChange     `json:"login_form,omitempty"`
+}
+
+// DiffSide names one of the compared analyses
+type DiffSide struct {
+	ID  string `json:"id,omitempty"` // of the history record, when stored
+	URL string `json:"url"`
+}
+
+// TextChange is a text that differs between the two analyses
+type TextChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// FlagChange is a property found in only one of the two analyses
+type FlagChange struct {
+	Before bool `json:"before"`
+	After  bool `json:"after"`
+}
+
+// HeadingChange is the count of a heading level that differs between the two analyses
+type HeadingChange struct {
+	Level  string `json:"level"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+}
+
+// ErrorChange is a page that fails in only one of the analyses, or fails differently
+type ErrorChange struct {
+	Before LinkError `json:"before"`
+	After  LinkError `json:"after"`
+}
+
+// Compare returns the differences between the before and after analyses.
+//
+// Links are matched by URL; internal links are matched by path and query only, so
+// that two deployments of a site on different hosts can be compared. A link is newly
+// broken when it is broken after and was not known broken before, and newly fixed
+// when it was broken before and is checked and accessible after. Links left
+// unchecked on either side are not reported.
+func Compare(before, after PageAnalysis) Diff {
+	d := Diff{
+		Before: DiffSide{ID: before.ID, URL: before.URL},
+		After:  DiffSide{ID: after.ID, URL: after.URL},
+	}
+
+	if failed(before) != failed(after) || before.Error.Kind != after.Error.Kind || before.Error.Status != after.Error.Status {
+		d.Error = &ErrorChange{Before: before.Error, After: after.Error}
+	}
+	if before.Title != after.Title {
+		d.Title = &TextChange{Before: before.Title, After: after.Title}
+	}
+	if before.HTMLVersion != after.HTMLVersion {
+		d.HTMLVersion = &TextChange{Before: before.HTMLVersion, After: after.HTMLVersion}
+	}
+	for i := 1; i <= 6; i++ {
+		level := fmt.Sprintf("h%d", i)
+		if b, a := before.HeadingsCount[level], after.HeadingsCount[level]; b != a {
+			d.Headings = append(d.Headings, HeadingChange{Level: level, Before: b, After: a})
+		}
+	}
+	if before.HasLoginForm != after.HasLoginForm {
+		d.LoginForm = &FlagChange{Before: before.HasLoginForm, After: after.HasLoginForm}
+	}
+
+	beforeLinks, beforeKeys := linksByKey(before)
+	afterLinks, afterKeys := linksByKey(after)
+	for _, key := range afterKeys {
+		link := afterLinks[key]
+		previous, found := beforeLinks[key]
+		if knownBroken(link) && !(found && knownBroken(previous)) {
+			d.NewlyBroken = append(d.NewlyBroken, link)
+		}
+	}
+	for _, key := range beforeKeys {
+		current, found := afterLinks[key]
+		if knownBroken(beforeLinks[key]) && found && current.Class != ClassNotChecked && !current.Class.Broken() {
+			d.NewlyFixed = append(d.NewlyFixed, current)
+		}
+	}
+
+	d.Changed = d.Error != nil || d.Title != nil || d.HTMLVersion != nil || len(d.Headings) > 0 ||
+		len(d.NewlyBroken) > 0 || len(d.NewlyFixed) > 0 || d.LoginForm != nil
+	d.Regressed = len(d.NewlyBroken) > 0 || (failed(after) && d.Error != nil)
+	for _, h := range d.Headings {
+		if h.After < h.Before {
+			d.Regressed = true
+		}
+	}
+	return d
+}
+
+func failed(a PageAnalysis) bool {
+	return a.Error.Message != ""
+}
+
+// knownBroken reports whether the link was checked and found broken
+func knownBroken(link LinkReport) bool {
+	return link.Class.Broken() && link.Class != ClassNotChecked
+}
+
+// linksByKey indexes the links of the analysis by their key, the first occurrence
+// winning, and returns the keys in page order
+func linksByKey(a PageAnalysis) (map[string]LinkReport, []string) {
+	links := make(map[string]LinkReport, len(a.Links))
+	var keys []string
+	for _, link := range a.Links {
+		key := linkKey(link)
+		if _, ok := links[key]; !ok {
+			links[key] = link
+			keys = append(keys, key)
+		}
+	}
+	return links, keys
+}
+
+// linkKey identifies a link across analyses: the path and query of internal links,
+// the URL of external ones
+func linkKey(link LinkReport) string {
+	if !link.Internal {
+		return link.URL
+	}
+	u, err := url.Parse(link.URL)
+	if err != nil {
+		return link.URL
+	}
+	key := u.EscapedPath()
+	if key == "" {
+		key = "/"
+	}
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key
+}
@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rabie/page-insight-tool/app/helper"
+)
+
+func link(url string, internal bool, class LinkClass) LinkReport {
+	return LinkReport{URL: url, Internal: internal, Accessible: !class.Broken(), Class: class}
+}
+
+func TestCompare_Unchanged(t *testing.T) {
+	a := PageAnalysis{
+		URL:           "https://example.com",
+		Title:         "Example",
+		HTMLVersion:   "HTML5",
+		HeadingsCount: map[string]int{"h1": 1},
+		Links:         []LinkReport{link("https://example.com/gone", true, ClassClientError)},
+	}
+	if d := Compare(a, a); d.Changed || d.Regressed {
+		t.Errorf("expected no change, got %+v", d)
+	}
+}
+
+func TestCompare_Changes(t *testing.T) {
+	before := PageAnalysis{
+		ID:            "before",
+		URL:           "https://example.com",
+		Title:         "Example",
+		HTMLVersion:   "HTML 4.01",
+		HeadingsCount: map[string]int{"h1": 1, "h2": 3},
+		Links: []LinkReport{
+			link("https://example.com/ok", true, ClassOK),
+			link("https://example.com/fixed", true, ClassServerError),
+			link("https://example.com/still-broken", true, ClassClientError),
+			link("https://other.org/", false, ClassOK),
+			link("https://example.com/unchecked", true, ClassNotChecked),
+		},
+	}
+	// The same site deployed on another host
+	after := PageAnalysis{
+		ID:            "after",
+		URL:           "https://staging.example.com",
+		Title:         "Example - Home",
+		HTMLVersion:   "HTML5",
+		HeadingsCount: map[string]int{"h2": 4},
+		HasLoginForm:  true,
+		Links: []LinkReport{
+			link("https://staging.example.com/ok", true, ClassOK),
+			link("https://staging.example.com/fixed", true, ClassOK),
+			link("https://staging.example.com/still-broken", true, ClassClientError),
+			link("https://other.org/", false, ClassNetworkError),
+			link("https://other.org/", false, ClassNetworkError),
+			link("https://staging.example.com/new", true, ClassClientError),
+			link("https://staging.example.com/unchecked", true, ClassOK),
+			link("https://staging.example.com/capped", true, ClassNotChecked),
+		},
+	}
+
+	d := Compare(before, after)
+	if !d.Changed || !d.Regressed {
+		t.Errorf("expected a regression, got changed=%v regressed=%v", d.Changed, d.Regressed)
+	}
+	if d.Before.ID != "before" || d.After.URL != "https://staging.example.com" {
+		t.Errorf("unexpected sides %+v %+v", d.Before, d.After)
+	}
+	if d.Title == nil || d.Title.After != "Example - Home" || d.HTMLVersion == nil || d.HTMLVersion.Before != "HTML 4.01" {
+		t.Errorf("expected title and HTML version changes, got %+v %+v", d.Title, d.HTMLVersion)
+	}
+	wantHeadings := []HeadingChange{{Level: "h1", Before: 1, After: 0}, {Level: "h2", Before: 3, After: 4}}
+	if !reflect.DeepEqual(d.Headings, wantHeadings) {
+		t.Errorf("expected heading changes %+v, got %+v", wantHeadings, d.Headings)
+	}
+	if d.LoginForm == nil || !d.LoginForm.After {
+		t.Errorf("expected the login form to appear, got %+v", d.LoginForm)
+	}
+	if d.Error != nil {
+		t.Errorf("expected no error change, got %+v", d.Error)
+	}
+
+	var broken, fixed []string
+	for _, l := range d.NewlyBroken {
+		broken = append(broken, l.URL)
+	}
+	for _, l := range d.NewlyFixed {
+		fixed = append(fixed, l.URL)
+	}
+	if want := []string{"https://other.org/", "https://staging.example.com/new"}; !reflect.DeepEqual(broken, want) {
+		t.Errorf("expected newly broken %v, got %v", want, broken)
+	}
+	if want := []string{"https://staging.example.com/fixed"}; !reflect.DeepEqual(fixed, want) {
+		t.Errorf("expected newly fixed %v, got %v", want, fixed)
+	}
+}
+
+func TestCompare_PageFails(t *testing.T) {
+	before := PageAnalysis{URL: "https://example.com", Title: "Example"}
+	after := PageAnalysis{URL: "https://example.com", Error: LinkError{Message: "Not Found", Status: 404, Kind: helper.ErrorKindClientError}}
+
+	d := Compare(before, after)
+	if d.Error == nil || d.Error.After.Status != 404 || !d.Regressed {
+		t.Errorf("expected the failure to be a regression, got %+v", d)
+	}
+	if d := Compare(after, before); d.Error == nil || d.Regressed {
+		t.Errorf("expected the recovery to be a change, not a regression, got %+v", d)
+	}
+}
@@ -0,0 +1,272 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/checker"
+	"github.com/rabie/page-insight-tool/app/helper"
+)
+
+const (
+	// maxLinkRetries is how many times a link is checked again after its host asked to slow down
+	maxLinkRetries = 1
+	// rateLimitPause is how long a host answering 429 without Retry-After is left alone
+	rateLimitPause = time.Second
+	// maxCheckBody is how much of a GET answer is read to recognize bot protection pages
+	maxCheckBody = 16 << 10
+)
+
+var errInvalidRequest = errors.New("invalid link")
+
+type linkResult struct {
+	link           *url.URL
+	isAccessible   bool
+	status         int
+	kind           helper.ErrorKind
+	message        string
+	retryAfter     time.Duration // delay asked by a 429 or 503 answer
+	method         string        // request method of the answer kept
+	redirected     bool
+	redirects      []helper.Hop
+	redirectIssues []helper.RedirectIssue
+	cached         bool
+}
+
+// checkLinksConcurrently checks links on the shared pool, keeping the input order.
+// Each check waits for the limits of its host; a host answering 429 or 503 with
+// Retry-After is paused and the link checked once more after the delay.
+// Links found in the result cache are reported first, without a request.
+// onResult, if set, is called from the calling goroutine as each check completes.
+// Links left unchecked because ctx is done or the pool is closed are reported as canceled.
+func (a *Analyzer) checkLinksConcurrently(ctx context.Context, links []*url.URL, onResult func(i int, res linkResult)) []linkResult {
+	results := make([]linkResult, len(links))
+	checked := make([]bool, len(links))
+	attempts := make([]int, len(links))
+
+	// pending maps the tasks given to the pool to the index of their link
+	var pending []int
+	var hosts []string
+	for i, link := range links {
+		if res, ok := a.cachedResult(link); ok {
+			slog.DebugContext(ctx, "link check cached", "url", link.String(), "status", res.status, "class", res.class())
+			results[i] = res
+			checked[i] = true
+			if onResult != nil {
+				onResult(i, res)
+			}
+			continue
+		}
+		pending = append(pending, i)
+		hosts = append(hosts, strings.ToLower(link.Hostname()))
+	}
+
+	done := make(chan int, len(pending))
+	var runErr error
+	go func() {
+		runErr = a.pool.RunHosts(ctx, hosts, func(task int) bool {
+			idx := pending[task]
+			start := time.Now()
+			res := a.checkLink(ctx, links[idx])
+			if res.retryAfter > 0 {
+				honored := a.pool.Pause(hosts[task], res.retryAfter)
+				if honored && attempts[idx] < maxLinkRetries {
+					slog.DebugContext(ctx, "link check deferred, host asked to slow down",
+						"url", res.link.String(), "status", res.status, "retry_after", res.retryAfter)
+					attempts[idx]++
+					return true
+				}
+			}
+			recordLinkCheck(res)
+			slog.DebugContext(ctx, "link checked",
+				"url", res.link.String(),
+				"method", res.method,
+				"status", res.status,
+				"class", res.class(),
+				"error_kind", res.kind,
+				"redirects", len(res.redirects),
+				"duration", time.Since(start),
+			)
+			if ctx.Err() == nil {
+				a.storeResult(res)
+			}
+			results[idx] = res
+			done <- idx
+			return false
+		})
+		close(done)
+	}()
+
+	for idx := range done {
+		checked[idx] = true
+		if onResult != nil {
+			onResult(idx, results[idx])
+		}
+	}
+
+	if runErr == nil {
+		// Unchecked links without an error from the pool: it closed meanwhile
+		runErr = checker.ErrClosed
+	}
+	for idx, ok := range checked {
+		if ok {
+			continue
+		}
+		results[idx] = linkResult{
+			link:    links[idx],
+			kind:    helper.ErrorKindCanceled,
+			message: runErr.Error(),
+		}
+		if onResult != nil {
+			onResult(idx, results[idx])
+		}
+	}
+
+	return results
+}
+
+// cachedResult returns the recent result of checking link, if any
+func (a *Analyzer) cachedResult(link *url.URL) (linkResult, bool) {
+	if a.cache == nil {
+		return linkResult{}, false
+	}
+	cached, ok := a.cache.Get(link)
+	if !ok {
+		return linkResult{}, false
+	}
+	return linkResult{
+		link:           link,
+		isAccessible:   cached.Accessible,
+		status:         cached.Status,
+		kind:           cached.Kind,
+		message:        cached.Message,
+		method:         cached.Method,
+		redirected:     len(cached.Redirects) > 0,
+		redirects:      cached.Redirects,
+		redirectIssues: cached.RedirectIssues,
+		cached:         true,
+	}, true
+}
+
+// storeResult caches the result of a completed check. Interrupted checks, unsupported
+// links, links refused by policy and hosts asking to slow down say nothing about the
+// link and are not kept.
+func (a *Analyzer) storeResult(res linkResult) {
+	if a.cache == nil || res.retryAfter > 0 {
+		return
+	}
+	switch res.kind {
+	case helper.ErrorKindCanceled, helper.ErrorKindUnsupported, helper.ErrorKindInvalidURL, helper.ErrorKindPolicy:
+		return
+	}
+	a.cache.Put(res.link, checker.Result{
+		Accessible:     res.isAccessible,
+		Status:         res.status,
+		Kind:           res.kind,
+		Message:        res.message,
+		Method:         res.method,
+		Redirects:      res.redirects,
+		RedirectIssues: res.redirectIssues,
+	})
+}
+
+// checkLink sends a HEAD request to the link and records how it answered. Servers
+// rejecting HEAD are asked again with a GET limited to the first bytes of the body.
+func (a *Analyzer) checkLink(ctx context.Context, link *url.URL) linkResult {
+	result := linkResult{link: link}
+
+	if err := ctx.Err(); err != nil {
+		result.kind = helper.ClassifyError(err)
+		result.message = err.Error()
+		return result
+	}
+
+	if link.Scheme == "mailto" || link.Scheme == "tel" || link.Scheme == "javascript" {
+		result.kind = helper.ErrorKindUnsupported
+		result.message = "unsupported link scheme"
+		return result
+	}
+	if link.Scheme == "" && link.Host == "" && strings.HasPrefix(link.String(), "#") {
+		result.kind = helper.ErrorKindUnsupported
+		result.message = "fragment-only link"
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.opts.LinkTimeout)
+	defer cancel()
+
+	result.method = http.MethodHead
+	res, body, trace, err := a.sendCheck(ctx, http.MethodHead, link)
+	if err == nil && headRejected(res.StatusCode) {
+		result.method = http.MethodGet
+		res, body, trace, err = a.sendCheck(ctx, http.MethodGet, link)
+	}
+	result.redirects = trace.Hops
+	result.redirectIssues = trace.Issues
+	result.redirected = trace.Redirected()
+	if err != nil {
+		result.kind = helper.ClassifyError(err)
+		if errors.Is(err, errInvalidRequest) {
+			result.kind = helper.ErrorKindInvalidURL
+		}
+		result.message = err.Error()
+		return result
+	}
+
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+		if d, ok := helper.ParseRetryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+			result.retryAfter = d
+		} else if res.StatusCode == http.StatusTooManyRequests {
+			result.retryAfter = rateLimitPause
+		}
+	}
+
+	result.status = res.StatusCode
+	// 416 answers the range of the fallback GET: the resource exists but is empty
+	result.isAccessible = res.StatusCode < 400 || (result.method == http.MethodGet && res.StatusCode == http.StatusRequestedRangeNotSatisfiable)
+	switch {
+	case result.isAccessible:
+	case botProtected(res, body):
+		result.kind = helper.ErrorKindBotBlocked
+		result.message = http.StatusText(res.StatusCode)
+	default:
+		result.kind = helper.ClassifyStatus(res.StatusCode)
+		result.message = http.StatusText(res.StatusCode)
+	}
+	return result
+}
+
+// sendCheck sends one check request, recording its redirects. GET requests only ask for
+// the first byte and read at most maxCheckBody bytes of the answer, kept to recognize bot
+// protection pages.
+func (a *Analyzer) sendCheck(ctx context.Context, method string, link *url.URL) (*http.Response, []byte, *helper.RedirectTrace, error) {
+	trace := helper.NewRedirectTrace(a.opts.MaxRedirects)
+	req, err := http.NewRequestWithContext(ctx, method, link.String(), nil)
+	if err != nil {
+		return nil, nil, trace, fmt.Errorf("%w: %v", errInvalidRequest, err)
+	}
+	req.Header.Set("User-Agent", a.opts.UserAgent)
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+
+	res, err := trace.Client(a.links).Do(req)
+	if err != nil {
+		return nil, nil, trace, err
+	}
+	defer res.Body.Close()
+	trace.Finish(res)
+
+	var body []byte
+	if method == http.MethodGet {
+		body, _ = io.ReadAll(io.LimitReader(res.Body, maxCheckBody))
+	}
+	return res, body, trace, nil
+}
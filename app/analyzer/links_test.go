@@ -0,0 +1,309 @@
+package analyzer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/checker"
+	"github.com/rabie/page-insight-tool/app/helper"
+)
+
+func TestCheckLink_Accessible(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	a := newTestAnalyzer(t)
+	tests := []struct {
+		link       string
+		accessible bool
+	}{
+		{server.URL + "/ok", true},
+		{server.URL + "/missing", false},
+		{"mailto:test@example.com", false},
+		{"tel:+1234567890", false},
+		{"javascript:alert('test')", false},
+		{"#section", false},
+	}
+	for _, tt := range tests {
+		link, _ := url.Parse(tt.link)
+		if got := a.checkLink(context.Background(), link).isAccessible; got != tt.accessible {
+			t.Errorf("%s: expected accessible %v, got %v", tt.link, tt.accessible, got)
+		}
+	}
+}
+
+func TestCheckLink_Status(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ok, _ := url.Parse(server.URL + "/ok")
+	if res := newTestAnalyzer(t).checkLink(context.Background(), ok); !res.isAccessible || res.status != http.StatusOK {
+		t.Errorf("expected accessible link with status 200, got %+v", res)
+	}
+
+	missing, _ := url.Parse(server.URL + "/missing")
+	res := newTestAnalyzer(t).checkLink(context.Background(), missing)
+	if res.isAccessible {
+		t.Error("expected 404 link to be inaccessible")
+	}
+	if res.status != http.StatusNotFound || res.kind != helper.ErrorKindClientError {
+		t.Errorf("expected 404 client error, got status %d kind %q", res.status, res.kind)
+	}
+}
+
+func TestCheckLinksConcurrently_Canceled(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	var links []*url.URL
+	for i := 0; i < 20; i++ {
+		link, _ := url.Parse(server.URL)
+		links = append(links, link)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := newTestAnalyzer(t).checkLinksConcurrently(ctx, links, nil)
+	for i, res := range results {
+		if res.isAccessible || res.kind != helper.ErrorKindCanceled {
+			t.Errorf("link %d: expected canceled result, got %+v", i, res)
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Errorf("expected no request after cancellation, got %d", n)
+	}
+}
+
+func TestCheckLink_CancelInFlight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	link, _ := url.Parse(server.URL)
+	start := time.Now()
+	res := newTestAnalyzer(t).checkLink(ctx, link)
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the check to stop with the context, took %s", elapsed)
+	}
+	if res.isAccessible || res.kind != helper.ErrorKindTimeout {
+		t.Errorf("expected timed out result, got %+v", res)
+	}
+}
+
+func TestCheckLinksConcurrently_PoolClosed(t *testing.T) {
+	pool := checker.NewPool(1)
+	pool.Close()
+	a := New(pool, nil, DefaultOptions())
+
+	link, _ := url.Parse("https://example.com")
+	results := a.checkLinksConcurrently(context.Background(), []*url.URL{link}, nil)
+
+	if len(results) != 1 || results[0].kind != helper.ErrorKindCanceled || results[0].link != link {
+		t.Errorf("expected a canceled result for the unchecked link, got %+v", results)
+	}
+}
+
+func TestCheckLinksConcurrently_RetryAfter(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	link, _ := url.Parse(server.URL)
+	start := time.Now()
+	results := newTestAnalyzer(t).checkLinksConcurrently(context.Background(), []*url.URL{link}, nil)
+
+	if !results[0].isAccessible || results[0].status != http.StatusOK {
+		t.Errorf("expected the retried link to be accessible, got %+v", results[0])
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected the retry to wait for Retry-After, took %s", elapsed)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestCheckLinksConcurrently_CloseDuringRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	pool := checker.NewPool(1)
+	opts := DefaultOptions()
+	opts.Transport = http.DefaultTransport
+	a := New(pool, nil, opts)
+	time.AfterFunc(100*time.Millisecond, pool.Close)
+
+	link, _ := url.Parse(server.URL)
+	results := a.checkLinksConcurrently(context.Background(), []*url.URL{link}, nil)
+	if results[0].kind != helper.ErrorKindCanceled || results[0].message != checker.ErrClosed.Error() {
+		t.Errorf("expected the link to be reported unchecked, got %+v", results[0])
+	}
+}
+
+func TestCheckLink_RetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/limited" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	limited, _ := url.Parse(server.URL + "/limited")
+	if res := newTestAnalyzer(t).checkLink(context.Background(), limited); res.retryAfter != rateLimitPause {
+		t.Errorf("expected a 429 without Retry-After to pause for %s, got %s", rateLimitPause, res.retryAfter)
+	}
+
+	unavailable, _ := url.Parse(server.URL + "/unavailable")
+	if res := newTestAnalyzer(t).checkLink(context.Background(), unavailable); res.retryAfter != 30*time.Second {
+		t.Errorf("expected a 30s pause, got %s", res.retryAfter)
+	}
+}
+
+func TestCheckLinksConcurrently_Cache(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	pool := checker.NewPool(2)
+	defer pool.Close()
+	opts := DefaultOptions()
+	opts.Transport = http.DefaultTransport
+	a := New(pool, checker.NewCache(checker.DefaultCacheOptions()), opts)
+
+	ok, _ := url.Parse(server.URL + "/privacy")
+	missing, _ := url.Parse(server.URL + "/missing")
+	links := []*url.URL{ok, missing}
+
+	first := a.checkLinksConcurrently(context.Background(), links, nil)
+	second := a.checkLinksConcurrently(context.Background(), links, nil)
+
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("expected the second analysis to be served from the cache, got %d requests", n)
+	}
+	for i := range links {
+		if first[i].cached || !second[i].cached {
+			t.Errorf("link %d: expected a network check then a cached result", i)
+		}
+		if first[i].isAccessible != second[i].isAccessible || first[i].status != second[i].status {
+			t.Errorf("link %d: cached result %+v differs from %+v", i, second[i], first[i])
+		}
+	}
+}
+
+func TestCheckLink_HeadFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case r.URL.Path == "/challenge":
+			w.Header().Set("Cf-Mitigated", "challenge")
+			w.WriteHeader(http.StatusForbidden)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			if r.Header.Get("Range") == "" {
+				t.Error("expected the fallback GET to ask for a range")
+			}
+			w.WriteHeader(http.StatusPartialContent)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path   string
+		method string
+		class  LinkClass
+	}{
+		{"/ok", http.MethodGet, ClassOK},
+		{"/missing", http.MethodGet, ClassClientError},
+		{"/moved", http.MethodGet, ClassRedirected},
+		{"/challenge", http.MethodGet, ClassBotProtected},
+	}
+
+	a := newTestAnalyzer(t)
+	for _, tt := range tests {
+		link, _ := url.Parse(server.URL + tt.path)
+		res := a.checkLink(context.Background(), link)
+		if res.method != tt.method || res.class() != tt.class {
+			t.Errorf("%s: expected %s check of class %q, got %s of class %q (%+v)", tt.path, tt.method, tt.class, res.method, res.class(), res)
+		}
+	}
+}
+
+func TestCheckLink_Redirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		case "/b":
+			http.Redirect(w, r, "/a", http.StatusFound)
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	a := newTestAnalyzer(t)
+
+	moved, _ := url.Parse(server.URL + "/moved")
+	res := a.checkLink(context.Background(), moved)
+	if res.class() != ClassRedirected || len(res.redirects) != 2 || res.redirects[1].Status != http.StatusOK {
+		t.Errorf("expected a traced redirect, got %+v", res)
+	}
+
+	loop, _ := url.Parse(server.URL + "/a")
+	res = a.checkLink(context.Background(), loop)
+	if res.isAccessible || res.kind != helper.ErrorKindRedirect {
+		t.Errorf("expected a redirect error, got %+v", res)
+	}
+	if len(res.redirectIssues) != 1 || res.redirectIssues[0] != helper.RedirectLoop {
+		t.Errorf("expected a loop to be flagged, got %v", res.redirectIssues)
+	}
+}
@@ -0,0 +1,35 @@
+package analyzer
+
+import (
+	"strconv"
+
+	"github.com/rabie/page-insight-tool/app/metrics"
+)
+
+// Analysis metrics, served on /metrics
+var (
+	analysesStarted = metrics.Default.NewCounter("page_insight_analyses_started_total",
+		"Page analyses started.")
+	analysesFinished = metrics.Default.NewCounter("page_insight_analyses_total",
+		"Page analyses finished, by result and error kind.", "result", "kind")
+	pageFetchSeconds = metrics.Default.NewHistogram("page_insight_page_fetch_duration_seconds",
+		"Time spent fetching and parsing the analyzed page, by error kind.", metrics.DefaultBuckets, "kind")
+	linkChecks = metrics.Default.NewCounter("page_insight_link_checks_total",
+		"Links checked with a request, by result class and status code (0 without an answer).", "class", "status")
+	ssrfRejections = metrics.Default.NewCounter("page_insight_ssrf_rejections_total",
+		"Analyzed URLs refused before any request, by error kind: blocked for reserved or denied networks, policy for the host policy.", "kind")
+)
+
+// recordAnalysis counts a finished analysis as succeeded or failed with its error kind
+func recordAnalysis(result PageAnalysis) {
+	if result.Error.Message == "" {
+		analysesFinished.Inc("succeeded", "")
+		return
+	}
+	analysesFinished.Inc("failed", string(result.Error.Kind))
+}
+
+// recordLinkCheck counts a link checked with a request
+func recordLinkCheck(res linkResult) {
+	linkChecks.Inc(string(res.class()), strconv.Itoa(res.status))
+}
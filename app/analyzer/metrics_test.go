@@ -0,0 +1,65 @@
+package analyzer
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rabie/page-insight-tool/app/checker"
+	"github.com/rabie/page-insight-tool/app/helper"
+)
+
+func TestAnalyzePage_Metrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><body><a href="/ok">OK</a><a href="/missing">Missing</a></body></html>`))
+		case "/ok":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	pool := checker.NewPool(2)
+	defer pool.Close()
+	a := New(pool, nil, Options{
+		Deny:      func(net.IP) bool { return false }, // the test server listens on loopback
+		Transport: http.DefaultTransport,
+	})
+
+	started := analysesStarted.Value()
+	succeeded := analysesFinished.Value("succeeded", "")
+	fetched := pageFetchSeconds.Count("")
+	ok := linkChecks.Value(string(ClassOK), "200")
+	missing := linkChecks.Value(string(ClassClientError), "404")
+
+	if analysis := a.AnalyzePage(context.Background(), server.URL); analysis.Error.Message != "" {
+		t.Fatalf("unexpected error %+v", analysis.Error)
+	}
+	if analysesStarted.Value() != started+1 || analysesFinished.Value("succeeded", "") != succeeded+1 {
+		t.Error("expected the analysis to be counted as started and succeeded")
+	}
+	if pageFetchSeconds.Count("") != fetched+1 {
+		t.Error("expected the page fetch latency to be observed")
+	}
+	if linkChecks.Value(string(ClassOK), "200") != ok+1 || linkChecks.Value(string(ClassClientError), "404") != missing+1 {
+		t.Error("expected the link checks to be counted by class and status")
+	}
+}
+
+func TestAnalyzePage_MetricsRejected(t *testing.T) {
+	blocked := ssrfRejections.Value(string(helper.ErrorKindBlocked))
+	failed := analysesFinished.Value("failed", string(helper.ErrorKindBlocked))
+
+	newTestAnalyzer(t).AnalyzePage(context.Background(), "http://192.168.1.1")
+
+	if ssrfRejections.Value(string(helper.ErrorKindBlocked)) != blocked+1 {
+		t.Error("expected the private address to be counted as an SSRF rejection")
+	}
+	if analysesFinished.Value("failed", string(helper.ErrorKindBlocked)) != failed+1 {
+		t.Error("expected the analysis to be counted as failed with its error kind")
+	}
+}
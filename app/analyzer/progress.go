@@ -0,0 +1,27 @@
+package analyzer
+
+// Analysis phases reported to progress listeners
+const (
+	PhaseFetch = "fetch"
+	PhaseParse = "parse"
+	PhaseLinks = "links"
+)
+
+// Progress is a snapshot of a running analysis
+type Progress struct {
+	Phase           string      `json:"phase"`
+	LinksDiscovered int         `json:"links_discovered"`
+	LinksChecked    int         `json:"links_checked"`
+	LinksBroken     int         `json:"links_broken"`
+	Link            *LinkReport `json:"link,omitempty"` // link checked in this step, if any
+}
+
+// ProgressFunc receives progress updates. Calls are made one at a time from the
+// goroutine running the analysis, so implementations should return quickly.
+type ProgressFunc func(Progress)
+
+func (f ProgressFunc) report(p Progress) {
+	if f != nil {
+		f(p)
+	}
+}
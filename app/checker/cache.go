@@ -0,0 +1,195 @@
+package checker
+
+import (
+	"container/list"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/helper"
+)
+
+// Result is the outcome of a link check kept by the cache
+type Result struct {
+	Accessible     bool
+	Status         int
+	Kind           helper.ErrorKind
+	Message        string
+	Method         string // request method of the answer, HEAD or the GET fallback
+	Redirects      []helper.Hop
+	RedirectIssues []helper.RedirectIssue
+	CheckedAt      time.Time
+}
+
+// CacheOptions bound the lifetime and the size of cached results
+type CacheOptions struct {
+	TTL         time.Duration // how long an accessible link is trusted
+	NegativeTTL time.Duration // how long an inaccessible link is trusted
+	MaxEntries  int           // results kept before the least recently used are evicted
+}
+
+// DefaultCacheOptions returns the options used when none are configured
+func DefaultCacheOptions() CacheOptions {
+	return CacheOptions{
+		TTL:         10 * time.Minute,
+		NegativeTTL: time.Minute,
+		MaxEntries:  10000,
+	}
+}
+
+// CacheStats counts the lookups served by the cache
+type CacheStats struct {
+	Entries int    `json:"entries"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
+type cacheEntry struct {
+	key    string
+	result Result
+}
+
+// Cache keeps recent link check results shared by every analysis, so the links
+// repeated across the pages of a site are checked once per TTL.
+type Cache struct {
+	opts CacheOptions
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+	hits    uint64
+	misses  uint64
+}
+
+// NewCache returns an empty cache
+func NewCache(opts CacheOptions) *Cache {
+	return &Cache{
+		opts:    withDefaults(opts),
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// SetOptions replaces the options of the cache. Cached results are kept, judged
+// by the new TTLs and evicted down to the new size.
+func (c *Cache) SetOptions(opts CacheOptions) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.opts = withDefaults(opts)
+	for c.order.Len() > c.opts.MaxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+func withDefaults(opts CacheOptions) CacheOptions {
+	defaults := DefaultCacheOptions()
+	if opts.TTL <= 0 {
+		opts.TTL = defaults.TTL
+	}
+	if opts.NegativeTTL <= 0 {
+		opts.NegativeTTL = defaults.NegativeTTL
+	}
+	if opts.MaxEntries < 1 {
+		opts.MaxEntries = defaults.MaxEntries
+	}
+	return opts
+}
+
+// Get returns the cached result for link if it has not expired yet
+func (c *Cache) Get(link *url.URL) (Result, bool) {
+	key := NormalizeURL(link)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return Result{}, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if c.expired(entry.result) {
+		c.remove(elem)
+		c.misses++
+		return Result{}, false
+	}
+	c.order.MoveToFront(elem)
+	c.hits++
+	return entry.result, true
+}
+
+// Put stores the result of checking link. A zero CheckedAt is set to the current time.
+func (c *Cache) Put(link *url.URL, result Result) {
+	key := NormalizeURL(link)
+	if result.CheckedAt.IsZero() {
+		result.CheckedAt = c.now()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*cacheEntry).result = result
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, result: result})
+	for c.order.Len() > c.opts.MaxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+// Stats returns the size of the cache and its hit counts
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{Entries: c.order.Len(), Hits: c.hits, Misses: c.misses}
+}
+
+// expired reports whether result is older than the TTL of its kind. Callers hold c.mu.
+func (c *Cache) expired(result Result) bool {
+	ttl := c.opts.TTL
+	if !result.Accessible {
+		ttl = c.opts.NegativeTTL
+	}
+	return c.now().Sub(result.CheckedAt) >= ttl
+}
+
+// remove drops elem from the cache. Callers hold c.mu.
+func (c *Cache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
+
+// NormalizeURL returns the cache key of link: lower-case scheme and host,
+// no default port, no fragment and "/" for an empty path
+func NormalizeURL(link *url.URL) string {
+	u := *link
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	if u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+	return u.String()
+}
@@ -0,0 +1,128 @@
+package checker
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"HTTPS://Example.COM", "https://example.com/"},
+		{"https://example.com:443/about#team", "https://example.com/about"},
+		{"http://example.com:80/?q=1", "http://example.com/?q=1"},
+		{"http://example.com:8080/a", "http://example.com:8080/a"},
+		{"http://[::1]:80/", "http://[::1]/"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeURL(mustParse(t, tt.raw)); got != tt.want {
+			t.Errorf("NormalizeURL(%q) = %q, expected %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestCache_GetPut(t *testing.T) {
+	c := NewCache(CacheOptions{})
+
+	if _, ok := c.Get(mustParse(t, "https://example.com/privacy")); ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+
+	c.Put(mustParse(t, "https://Example.com/privacy#top"), Result{Accessible: true, Status: 200})
+	res, ok := c.Get(mustParse(t, "https://example.com/privacy"))
+	if !ok || !res.Accessible || res.Status != 200 {
+		t.Fatalf("expected a cached 200, got %+v, %v", res, ok)
+	}
+	if res.CheckedAt.IsZero() {
+		t.Error("expected the check time to be recorded")
+	}
+
+	if stats := c.Stats(); stats.Entries != 1 || stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCache_TTL(t *testing.T) {
+	now := time.Now()
+	c := NewCache(CacheOptions{TTL: time.Hour, NegativeTTL: time.Minute})
+	c.now = func() time.Time { return now }
+
+	ok := mustParse(t, "https://example.com/ok")
+	broken := mustParse(t, "https://example.com/broken")
+	c.Put(ok, Result{Accessible: true, Status: 200})
+	c.Put(broken, Result{Status: 404})
+
+	now = now.Add(2 * time.Minute)
+	if _, hit := c.Get(ok); !hit {
+		t.Error("expected the accessible link to be cached for an hour")
+	}
+	if _, hit := c.Get(broken); hit {
+		t.Error("expected the broken link to expire after a minute")
+	}
+
+	now = now.Add(time.Hour)
+	if _, hit := c.Get(ok); hit {
+		t.Error("expected the accessible link to expire after an hour")
+	}
+	if stats := c.Stats(); stats.Entries != 0 {
+		t.Errorf("expected expired entries to be dropped, got %d", stats.Entries)
+	}
+}
+
+func TestCache_MaxEntries(t *testing.T) {
+	c := NewCache(CacheOptions{MaxEntries: 2})
+
+	a := mustParse(t, "https://example.com/a")
+	b := mustParse(t, "https://example.com/b")
+	d := mustParse(t, "https://example.com/d")
+	c.Put(a, Result{Accessible: true})
+	c.Put(b, Result{Accessible: true})
+	c.Get(a) // a is now more recently used than b
+	c.Put(d, Result{Accessible: true})
+
+	if _, ok := c.Get(b); ok {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+	if _, ok := c.Get(a); !ok {
+		t.Error("expected a recently used entry to be kept")
+	}
+	if _, ok := c.Get(d); !ok {
+		t.Error("expected the newest entry to be kept")
+	}
+}
+
+func TestCache_SetOptions(t *testing.T) {
+	c := NewCache(CacheOptions{MaxEntries: 3})
+
+	a := mustParse(t, "https://example.com/a")
+	b := mustParse(t, "https://example.com/b")
+	c.Put(a, Result{Accessible: true})
+	c.Put(b, Result{Accessible: true})
+
+	c.SetOptions(CacheOptions{MaxEntries: 1})
+	if _, ok := c.Get(a); ok {
+		t.Error("expected the cache to shrink to its new size")
+	}
+	if _, ok := c.Get(b); !ok {
+		t.Error("expected the most recent entry to be kept")
+	}
+
+	c.SetOptions(CacheOptions{MaxEntries: 1, TTL: time.Nanosecond})
+	time.Sleep(time.Millisecond)
+	if _, ok := c.Get(b); ok {
+		t.Error("expected the new TTL to apply to cached results")
+	}
+}
@@ -0,0 +1,93 @@
+package checker
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultHostConcurrency is the number of checks run at the same time against one host
+	DefaultHostConcurrency = 4
+	// DefaultMaxPause caps how long a host asking to slow down is left alone
+	DefaultMaxPause = 30 * time.Second
+)
+
+// HostRule limits the checks sent to the hosts matching Pattern.
+// Pattern is an exact host name, a wildcard suffix such as "*.example.com"
+// (matching example.com and all its subdomains) or "*" for every host.
+type HostRule struct {
+	Pattern       string
+	MaxConcurrent int           // checks running at the same time against one host, 0 for no limit
+	Interval      time.Duration // minimum delay between two checks started against one host
+}
+
+// HostLimits holds the politeness rules applied to each host. The first rule
+// matching a host wins; hosts matching none use Default.
+type HostLimits struct {
+	Rules    []HostRule
+	Default  HostRule
+	MaxPause time.Duration // longest Retry-After honored by Pause
+}
+
+// DefaultHostLimits returns the limits used when none are configured
+func DefaultHostLimits() HostLimits {
+	return HostLimits{
+		Default:  HostRule{Pattern: "*", MaxConcurrent: DefaultHostConcurrency},
+		MaxPause: DefaultMaxPause,
+	}
+}
+
+// ruleFor returns the rule applying to host
+func (l HostLimits) ruleFor(host string) HostRule {
+	for _, rule := range l.Rules {
+		if rule.matches(host) {
+			return rule
+		}
+	}
+	return l.Default
+}
+
+// matches reports whether host falls under the rule's pattern
+func (r HostRule) matches(host string) bool {
+	pattern := strings.ToLower(strings.TrimSpace(r.Pattern))
+	host = strings.ToLower(host)
+
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasPrefix(pattern, "*."):
+		domain := pattern[2:]
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	default:
+		return host == pattern
+	}
+}
+
+// hostState tracks the checks running against one host
+type hostState struct {
+	rule    HostRule
+	running int
+	next    time.Time // earliest start of the next check allowed by the rule's interval
+	paused  time.Time // end of the pause asked by the host
+}
+
+// free reports whether a check may start against the host at now
+func (h *hostState) free(now time.Time) bool {
+	if h.rule.MaxConcurrent > 0 && h.running >= h.rule.MaxConcurrent {
+		return false
+	}
+	return !now.Before(h.next) && !now.Before(h.paused)
+}
+
+// until returns when the host accepts checks again, as far as time is concerned
+func (h *hostState) until() time.Time {
+	if h.paused.After(h.next) {
+		return h.paused
+	}
+	return h.next
+}
+
+// idle reports whether the state can be forgotten without loosening any limit
+func (h *hostState) idle(now time.Time) bool {
+	return h.running == 0 && !now.Before(h.until())
+}
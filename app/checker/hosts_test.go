@@ -0,0 +1,47 @@
+package checker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHostRule_Matches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		host    string
+		want    bool
+	}{
+		{"*", "example.com", true},
+		{"example.com", "example.com", true},
+		{"example.com", "EXAMPLE.com", true},
+		{"example.com", "www.example.com", false},
+		{"*.example.com", "example.com", true},
+		{"*.example.com", "cdn.static.example.com", true},
+		{"*.example.com", "badexample.com", false},
+		{"*.example.com", "example.org", false},
+	}
+
+	for _, tt := range tests {
+		if got := (HostRule{Pattern: tt.pattern}).matches(tt.host); got != tt.want {
+			t.Errorf("pattern %q on host %q: expected %v, got %v", tt.pattern, tt.host, tt.want, got)
+		}
+	}
+}
+
+func TestHostLimits_RuleFor(t *testing.T) {
+	limits := DefaultHostLimits()
+	limits.Rules = []HostRule{
+		{Pattern: "api.example.com", MaxConcurrent: 1},
+		{Pattern: "*.example.com", MaxConcurrent: 2, Interval: time.Second},
+	}
+
+	if rule := limits.ruleFor("api.example.com"); rule.MaxConcurrent != 1 {
+		t.Errorf("expected the first matching rule to win, got %+v", rule)
+	}
+	if rule := limits.ruleFor("www.example.com"); rule.MaxConcurrent != 2 || rule.Interval != time.Second {
+		t.Errorf("expected the wildcard rule, got %+v", rule)
+	}
+	if rule := limits.ruleFor("example.org"); rule.MaxConcurrent != DefaultHostConcurrency {
+		t.Errorf("expected the default rule, got %+v", rule)
+	}
+}
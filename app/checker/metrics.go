@@ -0,0 +1,20 @@
+package checker
+
+import "github.com/rabie/page-insight-tool/app/metrics"
+
+// RegisterMetrics reports the occupancy of the pool on r, read from Stats on every scrape
+func (p *Pool) RegisterMetrics(r *metrics.Registry) {
+	gauge := func(name, help string, value func(Stats) int) {
+		r.NewGaugeFunc(name, help, func() float64 { return float64(value(p.Stats())) })
+	}
+	gauge("page_insight_link_checker_workers", "Link checks allowed to run at the same time.",
+		func(s Stats) int { return s.Workers })
+	gauge("page_insight_link_checker_busy", "Link checks running.",
+		func(s Stats) int { return s.Busy })
+	gauge("page_insight_link_checker_queued", "Link checks waiting for a worker.",
+		func(s Stats) int { return s.Queued })
+	gauge("page_insight_link_checker_analyses", "Analyses with link checks in the pool.",
+		func(s Stats) int { return s.Analyses })
+	gauge("page_insight_link_checker_paused_hosts", "Hosts paused after asking to slow down.",
+		func(s Stats) int { return s.PausedHosts })
+}
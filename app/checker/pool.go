@@ -0,0 +1,393 @@
+// Package checker provides the process-wide pool that runs outbound link checks
+package checker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/config"
+)
+
+// ErrClosed is returned by Run once the pool has been closed
+var ErrClosed = errors.New("link checker is shut down")
+
+// Stats is a snapshot of the pool's occupancy
+type Stats struct {
+	Workers     int `json:"workers"`
+	Busy        int `json:"busy"`
+	Queued      int `json:"queued"`
+	Analyses    int `json:"analyses"`
+	PausedHosts int `json:"paused_hosts"`
+}
+
+// session holds the tasks submitted by one Run call
+type session struct {
+	ctx      context.Context
+	task     func(i int) bool
+	hosts    []string
+	pending  []int // indexes of the tasks not dispatched yet, in order
+	running  int
+	finished bool
+	dropped  bool // a task asking to be retried was discarded
+	done     chan struct{}
+}
+
+// Pool runs tasks on a fixed number of workers shared by every analysis.
+// Workers serve the pending analyses round-robin, one task at a time, so a page
+// with hundreds of links cannot starve the analyses submitted after it.
+// Tasks bound to a host also obey that host's limits: a task whose host is busy
+// or paused is skipped until the host is free again.
+type Pool struct {
+	wg sync.WaitGroup
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	ready   []*session // sessions with tasks left to dispatch
+	turn    int        // index in ready of the session served next
+	limits  HostLimits
+	hosts   map[string]*hostState
+	wake    time.Time // when the pending wake-up timer fires
+	workers int       // target number of workers
+	spawned int       // worker goroutines alive, above workers while extra ones wind down
+	busy    int
+	queued  int
+	active  int
+	closed  bool
+}
+
+// NewPool starts a pool with the given number of workers and the default host limits
+func NewPool(workers int) *Pool {
+	if workers < 1 {
+		workers = config.DefaultLinkCheckWorkers
+	}
+	p := &Pool{
+		workers: workers,
+		limits:  DefaultHostLimits(),
+		hosts:   make(map[string]*hostState),
+	}
+	p.cond = sync.NewCond(&p.mu)
+
+	p.mu.Lock()
+	p.spawn()
+	p.mu.Unlock()
+	return p
+}
+
+// SetWorkers changes the number of workers. Extra workers stop once their current
+// task has returned; tasks already running are never interrupted.
+func (p *Pool) SetWorkers(workers int) {
+	if workers < 1 {
+		workers = config.DefaultLinkCheckWorkers
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+	p.workers = workers
+	p.spawn()
+	p.cond.Broadcast()
+}
+
+// spawn starts the workers missing to reach p.workers. Callers hold p.mu.
+func (p *Pool) spawn() {
+	for ; p.spawned < p.workers; p.spawned++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+}
+
+// SetHostLimits replaces the per-host limits, including for the hosts being checked
+func (p *Pool) SetHostLimits(limits HostLimits) {
+	if limits.Default.Pattern == "" {
+		limits.Default = DefaultHostLimits().Default
+	}
+	if limits.MaxPause <= 0 {
+		limits.MaxPause = DefaultMaxPause
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.limits = limits
+	for host, state := range p.hosts {
+		state.rule = limits.ruleFor(host)
+	}
+	p.cond.Broadcast()
+}
+
+// Run schedules n tasks for one analysis and blocks until they have all run.
+// When ctx is done the tasks not started yet are dropped and ctx.Err() is returned
+// once the running ones have returned; tasks are expected to watch ctx themselves.
+func (p *Pool) Run(ctx context.Context, n int, task func(i int)) error {
+	return p.RunHosts(ctx, make([]string, n), func(i int) bool {
+		task(i)
+		return false
+	})
+}
+
+// RunHosts is Run for tasks bound to hosts: task i sends its requests to hosts[i]
+// and waits for that host's limits. An empty host has no limits. A task returning
+// true is queued again, typically after calling Pause because the host asked to slow down;
+// when the pool closes first, the task is dropped and ErrClosed is returned.
+func (p *Pool) RunHosts(ctx context.Context, hosts []string, task func(i int) (retry bool)) error {
+	if len(hosts) == 0 {
+		return nil
+	}
+	s := &session{ctx: ctx, task: task, hosts: hosts, done: make(chan struct{})}
+	s.pending = make([]int, len(hosts))
+	for i := range s.pending {
+		s.pending[i] = i
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.ready = append(p.ready, s)
+	p.queued += len(hosts)
+	p.active++
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return s.err()
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	p.drop(s)
+	p.mu.Unlock()
+
+	<-s.done
+	return ctx.Err()
+}
+
+// err tells why the tasks of a finished session did not all run, nil when they did
+func (s *session) err() error {
+	if !s.dropped {
+		return nil
+	}
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	return ErrClosed
+}
+
+// Pause keeps any new check away from host for d, as asked by a Retry-After header.
+// Delays longer than the configured maximum are cut short; Pause reports whether d was honored in full.
+func (p *Pool) Pause(host string, d time.Duration) bool {
+	if host == "" || d <= 0 {
+		return true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	honored := d <= p.limits.MaxPause
+	if !honored {
+		d = p.limits.MaxPause
+	}
+	state := p.host(host)
+	if until := time.Now().Add(d); until.After(state.paused) {
+		state.paused = until
+	}
+	return honored
+}
+
+// Stats returns the current occupancy of the pool
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	paused := 0
+	for _, state := range p.hosts {
+		if now.Before(state.paused) {
+			paused++
+		}
+	}
+	return Stats{
+		Workers:     p.workers,
+		Busy:        p.busy,
+		Queued:      p.queued,
+		Analyses:    p.active,
+		PausedHosts: paused,
+	}
+}
+
+// Close stops accepting work and waits for the queued tasks to run
+func (p *Pool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		for len(p.ready) == 0 && !p.closed && p.spawned <= p.workers {
+			p.cond.Wait()
+		}
+		if len(p.ready) == 0 && p.closed {
+			p.spawned--
+			return
+		}
+		if p.spawned > p.workers {
+			p.spawned--
+			return
+		}
+
+		now := time.Now()
+		s, i := p.pick(now)
+		if s == nil {
+			p.sleepUntilFree(now)
+			p.cond.Wait()
+			continue
+		}
+
+		var state *hostState
+		if host := s.hosts[i]; host != "" {
+			state = p.host(host)
+			state.running++
+			if state.rule.Interval > 0 {
+				state.next = now.Add(state.rule.Interval)
+			}
+		}
+		p.queued--
+		p.busy++
+		s.running++
+
+		p.mu.Unlock()
+		retry := s.task(i)
+		p.mu.Lock()
+
+		p.busy--
+		s.running--
+		if state != nil {
+			state.running--
+			if state.idle(time.Now()) {
+				delete(p.hosts, s.hosts[i])
+			}
+		}
+		if retry && s.ctx.Err() == nil && !p.closed {
+			if len(s.pending) == 0 {
+				p.ready = append(p.ready, s)
+			}
+			s.pending = append(s.pending, i)
+			p.queued++
+		} else if retry {
+			s.dropped = true
+		}
+		p.finish(s)
+		p.cond.Broadcast()
+	}
+}
+
+// pick returns the next task to run, serving the sessions round-robin and taking from
+// each its first task whose host is free. It returns a nil session when every queued
+// task waits for its host. Callers hold p.mu.
+func (p *Pool) pick(now time.Time) (*session, int) {
+	for k := 0; k < len(p.ready); k++ {
+		idx := (p.turn + k) % len(p.ready)
+		s := p.ready[idx]
+		if s.ctx.Err() != nil {
+			p.drop(s)
+			return p.pick(now)
+		}
+
+		for j, i := range s.pending {
+			if host := s.hosts[i]; host != "" && !p.host(host).free(now) {
+				continue
+			}
+			s.pending = append(s.pending[:j], s.pending[j+1:]...)
+			p.turn = idx + 1
+			if len(s.pending) == 0 {
+				p.removeReady(s)
+			}
+			return s, i
+		}
+	}
+	return nil, 0
+}
+
+// host returns the state of host, creating it on first use. Callers hold p.mu.
+func (p *Pool) host(host string) *hostState {
+	state, ok := p.hosts[host]
+	if !ok {
+		state = &hostState{rule: p.limits.ruleFor(host)}
+		p.hosts[host] = state
+	}
+	return state
+}
+
+// sleepUntilFree arms a timer waking the workers when the first paused host becomes free.
+// Hosts held back by their concurrency limit wake the workers when a task returns. Callers hold p.mu.
+func (p *Pool) sleepUntilFree(now time.Time) {
+	var first time.Time
+	for host, state := range p.hosts {
+		if state.idle(now) {
+			delete(p.hosts, host)
+			continue
+		}
+		if until := state.until(); until.After(now) && (first.IsZero() || until.Before(first)) {
+			first = until
+		}
+	}
+	if first.IsZero() || (p.wake.After(now) && !first.Before(p.wake)) {
+		return
+	}
+
+	p.wake = first
+	time.AfterFunc(first.Sub(now), func() {
+		p.mu.Lock()
+		p.cond.Broadcast()
+		p.mu.Unlock()
+	})
+}
+
+// drop discards the tasks of s not dispatched yet. Callers hold p.mu.
+func (p *Pool) drop(s *session) {
+	if len(s.pending) > 0 {
+		p.queued -= len(s.pending)
+		s.pending = nil
+		p.removeReady(s)
+	}
+	p.finish(s)
+}
+
+// removeReady takes s out of the round-robin. Callers hold p.mu.
+func (p *Pool) removeReady(s *session) {
+	for i, r := range p.ready {
+		if r == s {
+			p.ready = append(p.ready[:i], p.ready[i+1:]...)
+			if i < p.turn {
+				p.turn--
+			}
+			return
+		}
+	}
+}
+
+// finish releases Run once every task of s has been dispatched and has returned. Callers hold p.mu.
+func (p *Pool) finish(s *session) {
+	if s.finished || len(s.pending) > 0 || s.running > 0 {
+		return
+	}
+	s.finished = true
+	p.active--
+	close(s.done)
+}
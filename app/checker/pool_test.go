@@ -0,0 +1,354 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/metrics"
+)
+
+func TestPool_RunsEveryTask(t *testing.T) {
+	p := NewPool(4)
+	defer p.Close()
+
+	var count int32
+	seen := make([]int32, 100)
+	if err := p.Run(context.Background(), len(seen), func(i int) {
+		atomic.AddInt32(&count, 1)
+		atomic.AddInt32(&seen[i], 1)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 100 {
+		t.Errorf("expected 100 tasks to run, got %d", count)
+	}
+	for i, n := range seen {
+		if n != 1 {
+			t.Errorf("task %d ran %d times", i, n)
+		}
+	}
+	if stats := p.Stats(); stats.Busy != 0 || stats.Queued != 0 || stats.Analyses != 0 {
+		t.Errorf("expected an idle pool, got %+v", stats)
+	}
+}
+
+func TestPool_GlobalLimit(t *testing.T) {
+	p := NewPool(3)
+	defer p.Close()
+
+	var running, peak int32
+	task := func(i int) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = p.Run(context.Background(), 10, task)
+		}()
+	}
+	wg.Wait()
+
+	if peak > 3 {
+		t.Errorf("expected at most 3 concurrent tasks, saw %d", peak)
+	}
+}
+
+func TestPool_FairScheduling(t *testing.T) {
+	p := NewPool(1)
+	defer p.Close()
+
+	// Hold the only worker so both analyses are queued before anything runs
+	release := make(chan struct{})
+	blocked := make(chan struct{})
+	go func() {
+		_ = p.Run(context.Background(), 1, func(int) {
+			close(blocked)
+			<-release
+		})
+	}()
+	<-blocked
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) func(int) {
+		return func(int) {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); _ = p.Run(context.Background(), 6, record("big")) }()
+	waitForQueued(t, p, 6)
+	go func() { defer wg.Done(); _ = p.Run(context.Background(), 2, record("small")) }()
+	waitForQueued(t, p, 8)
+
+	close(release)
+	wg.Wait()
+
+	// The small analysis must not wait for the whole big one
+	want := []string{"big", "small", "big", "small", "big", "big", "big", "big"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("expected round-robin order %v, got %v", want, order)
+		}
+	}
+}
+
+func TestPool_CancelDropsQueuedTasks(t *testing.T) {
+	p := NewPool(1)
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	var count int32
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p.Run(ctx, 10, func(i int) {
+			if atomic.AddInt32(&count, 1) == 1 {
+				close(started)
+			}
+			<-ctx.Done()
+		})
+	}()
+
+	<-started
+	cancel()
+
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected queued tasks to be dropped, %d ran", count)
+	}
+	if stats := p.Stats(); stats.Queued != 0 || stats.Analyses != 0 {
+		t.Errorf("expected an idle pool after cancellation, got %+v", stats)
+	}
+}
+
+func TestPool_Closed(t *testing.T) {
+	p := NewPool(1)
+	p.Close()
+
+	if err := p.Run(context.Background(), 1, func(int) {}); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestPool_CloseDropsRetries(t *testing.T) {
+	p := NewPool(1)
+	started := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p.RunHosts(context.Background(), []string{"a.example"}, func(int) bool {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			return true
+		})
+	}()
+	<-started
+	p.Close()
+
+	if err := <-errc; !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed for the dropped retry, got %v", err)
+	}
+}
+
+func waitForQueued(t *testing.T, p *Pool, queued int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for p.Stats().Queued != queued {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d queued tasks, got %+v", queued, p.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPool_HostConcurrency(t *testing.T) {
+	p := NewPool(8)
+	defer p.Close()
+	p.SetHostLimits(HostLimits{Rules: []HostRule{{Pattern: "slow.test", MaxConcurrent: 2}}})
+
+	hosts := make([]string, 20)
+	for i := range hosts {
+		hosts[i] = "slow.test"
+		if i%2 == 1 {
+			hosts[i] = "other.test"
+		}
+	}
+
+	var mu sync.Mutex
+	running := map[string]int{}
+	peak := map[string]int{}
+	err := p.RunHosts(context.Background(), hosts, func(i int) bool {
+		mu.Lock()
+		running[hosts[i]]++
+		if running[hosts[i]] > peak[hosts[i]] {
+			peak[hosts[i]] = running[hosts[i]]
+		}
+		mu.Unlock()
+		time.Sleep(2 * time.Millisecond)
+		mu.Lock()
+		running[hosts[i]]--
+		mu.Unlock()
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if peak["slow.test"] > 2 {
+		t.Errorf("expected at most 2 concurrent checks on slow.test, got %d", peak["slow.test"])
+	}
+	if peak["other.test"] <= 2 {
+		t.Errorf("expected other.test to use the default limit, got a peak of %d", peak["other.test"])
+	}
+}
+
+func TestPool_HostInterval(t *testing.T) {
+	p := NewPool(4)
+	defer p.Close()
+	p.SetHostLimits(HostLimits{Default: HostRule{Pattern: "*", Interval: 10 * time.Millisecond}})
+
+	var mu sync.Mutex
+	var starts []time.Time
+	err := p.RunHosts(context.Background(), []string{"a.test", "a.test", "a.test"}, func(i int) bool {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < 9*time.Millisecond {
+			t.Errorf("expected checks 10ms apart, got %v between check %d and %d", gap, i-1, i)
+		}
+	}
+}
+
+func TestPool_PauseAndRetry(t *testing.T) {
+	p := NewPool(2)
+	defer p.Close()
+
+	var mu sync.Mutex
+	attempts := map[int]int{}
+	var retried time.Time
+	var paused time.Time
+	err := p.RunHosts(context.Background(), []string{"busy.test", "busy.test"}, func(i int) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[i]++
+		if i == 0 && attempts[i] == 1 {
+			paused = time.Now()
+			p.Pause("busy.test", 20*time.Millisecond)
+			return true
+		}
+		if i == 0 {
+			retried = time.Now()
+		}
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts[0] != 2 || attempts[1] != 1 {
+		t.Errorf("expected task 0 to run twice and task 1 once, got %v", attempts)
+	}
+	if gap := retried.Sub(paused); gap < 19*time.Millisecond {
+		t.Errorf("expected the retry to wait for the pause, it ran after %v", gap)
+	}
+	if stats := p.Stats(); stats.Queued != 0 || stats.Analyses != 0 {
+		t.Errorf("expected an idle pool, got %+v", stats)
+	}
+}
+
+func TestPool_PauseCapped(t *testing.T) {
+	p := NewPool(1)
+	defer p.Close()
+	p.SetHostLimits(HostLimits{MaxPause: time.Second})
+
+	if !p.Pause("a.test", 10*time.Millisecond) {
+		t.Error("expected a short pause to be honored")
+	}
+	if p.Pause("a.test", time.Hour) {
+		t.Error("expected a pause above the maximum to be cut short")
+	}
+	if stats := p.Stats(); stats.PausedHosts != 1 {
+		t.Errorf("expected 1 paused host, got %d", stats.PausedHosts)
+	}
+}
+
+func TestPool_SetWorkers(t *testing.T) {
+	p := NewPool(1)
+	defer p.Close()
+
+	peakOf := func() int32 {
+		var running, peak int32
+		_ = p.Run(context.Background(), 12, func(i int) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		})
+		return peak
+	}
+
+	p.SetWorkers(3)
+	if peak := peakOf(); peak != 3 {
+		t.Errorf("expected 3 tasks at once after growing the pool, got %d", peak)
+	}
+	p.SetWorkers(1)
+	if peak := peakOf(); peak != 1 {
+		t.Errorf("expected 1 task at once after shrinking the pool, got %d", peak)
+	}
+	if stats := p.Stats(); stats.Workers != 1 {
+		t.Errorf("expected 1 worker, got %+v", stats)
+	}
+}
+
+func TestPool_RegisterMetrics(t *testing.T) {
+	p := NewPool(3)
+	defer p.Close()
+	r := metrics.NewRegistry()
+	p.RegisterMetrics(r)
+
+	var out strings.Builder
+	if _, err := r.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"page_insight_link_checker_workers 3\n", "page_insight_link_checker_queued 0\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in the metrics, got:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// Package cli implements the command-line subcommands of the Page Insight Tool
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/checker"
+	"github.com/rabie/page-insight-tool/app/config"
+	"github.com/rabie/page-insight-tool/app/helper"
+)
+
+// Exit codes returned by the subcommands
+const (
+	ExitOK        = 0
+	ExitFailed    = 1
+	ExitUsage     = 2
+	ExitThreshold = 3
+)
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// Analyze runs `page-insight-tool analyze <url>` and returns the process exit code.
+// Cancelling ctx aborts the analysis.
+func Analyze(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("analyze", stderr)
+	format := fs.String("format", formatText, "output format: text or json")
+	maxBroken := fs.Int("max-broken", -1, "fail when more links than this are inaccessible (-1 disables the check)")
+	timeout := fs.Duration("timeout", 0, "abort the analysis after this duration (0 means no limit)")
+	configFile := configFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: page-insight-tool analyze <url> [--format text|json] [--max-broken N] [--timeout D] [--config path]")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return ExitUsage
+	}
+	if *format != formatText && *format != formatJSON {
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return ExitUsage
+	}
+
+	a, err := newAnalyzer(*configFile)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailed
+	}
+
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
+
+	result := a.AnalyzePage(ctx, positional[0])
+
+	if *format == formatJSON {
+		err = writeJSON(stdout, result)
+	} else {
+		err = writeText(stdout, result)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailed
+	}
+
+	if result.Error.Message != "" {
+		return ExitFailed
+	}
+	if *maxBroken >= 0 && result.InaccessibleLinks > *maxBroken {
+		fmt.Fprintf(stderr, "%d inaccessible links exceed the threshold of %d\n", result.InaccessibleLinks, *maxBroken)
+		return ExitThreshold
+	}
+	return ExitOK
+}
+
+// configFlag registers the --config flag of the subcommands that analyze pages
+func configFlag(fs *flag.FlagSet) *string {
+	return fs.String("config", "", "configuration file of the analysis settings, read like the server's (default: environment variables and built-in defaults)")
+}
+
+// newAnalyzer returns an analyzer with its own link checker pool and result cache for
+// this process, set up like the server's from the APP_ENV environment of configFile
+// and the environment variables
+func newAnalyzer(configFile string) (*analyzer.Analyzer, error) {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := AnalyzerOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return analyzer.New(checker.NewPool(cfg.LinkCheckWorkers), checker.NewCache(CacheOptions(cfg)), opts), nil
+}
+
+// AnalyzerOptions returns the analysis options of cfg
+func AnalyzerOptions(cfg *config.Config) (analyzer.Options, error) {
+	denyList, err := helper.NewIPDenyList(cfg.DeniedNetworks)
+	if err != nil {
+		return analyzer.Options{}, err
+	}
+	hostPolicy, err := helper.NewHostPolicy(cfg.HostPolicy.Allow, cfg.HostPolicy.Deny)
+	if err != nil {
+		return analyzer.Options{}, err
+	}
+	return analyzer.Options{
+		PageTimeout:    cfg.PageTimeout,
+		LinkTimeout:    cfg.LinkTimeout,
+		UserAgent:      cfg.UserAgent,
+		MaxLinks:       cfg.MaxLinks,
+		MaxRedirects:   cfg.MaxRedirects,
+		Deny:           denyList.Denies,
+		Policy:         hostPolicy,
+		PolicyForLinks: cfg.HostPolicy.ApplyToLinks,
+	}, nil
+}
+
+// CacheOptions returns the link cache options of cfg
+func CacheOptions(cfg *config.Config) checker.CacheOptions {
+	return checker.CacheOptions{
+		TTL:         cfg.LinkCache.TTL,
+		NegativeTTL: cfg.LinkCache.NegativeTTL,
+		MaxEntries:  cfg.LinkCache.MaxEntries,
+	}
+}
+
+// withTimeout bounds ctx by d unless d is zero
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+	return context.WithCancel(ctx)
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// parseArgs parses flags that may appear before or after the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeText(w io.Writer, result analyzer.PageAnalysis) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "URL:\t%s\n", result.URL)
+	if len(result.Redirects) > 0 {
+		fmt.Fprintf(tw, "Redirects:\t%s\n", formatHops(result.Redirects))
+	}
+	if len(result.RedirectIssues) > 0 {
+		fmt.Fprintf(tw, "Redirect issues:\t%s\n", formatIssues(result.RedirectIssues))
+	}
+
+	if result.Error.Message != "" {
+		fmt.Fprintf(tw, "Error:\t%s\n", result.Error.Message)
+		if result.Error.Status != 0 {
+			fmt.Fprintf(tw, "Status:\t%d\n", result.Error.Status)
+		}
+		if result.Error.Explanation != "" {
+			fmt.Fprintf(tw, "Explanation:\t%s\n", result.Error.Explanation)
+		}
+		return tw.Flush()
+	}
+
+	title := result.Title
+	if title == "" {
+		title = "No title found"
+	}
+	fmt.Fprintf(tw, "Title:\t%s\n", title)
+	fmt.Fprintf(tw, "HTML version:\t%s\n", result.HTMLVersion)
+	fmt.Fprint(tw, "Headings:\t")
+	for i := 1; i <= 6; i++ {
+		h := fmt.Sprintf("h%d", i)
+		fmt.Fprintf(tw, "%s=%d ", h, result.HeadingsCount[h])
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "Internal links:\t%d\n", result.InternalLinks)
+	fmt.Fprintf(tw, "External links:\t%d\n", result.ExternalLinks)
+	fmt.Fprintf(tw, "Inaccessible links:\t%d\n", result.InaccessibleLinks)
+	if len(result.LinkClasses) > 0 {
+		fmt.Fprint(tw, "Link classes:\t")
+		for _, class := range analyzer.AllLinkClasses {
+			if n := result.LinkClasses[class]; n > 0 {
+				fmt.Fprintf(tw, "%s=%d ", class, n)
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	fmt.Fprintf(tw, "Login form:\t%s\n", yesNo(result.HasLoginForm))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var flagged []analyzer.LinkReport
+	for _, link := range result.Links {
+		if len(link.RedirectIssues) > 0 {
+			flagged = append(flagged, link)
+		}
+	}
+	if len(flagged) > 0 {
+		fmt.Fprintln(w, "\nRedirect issues:")
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, link := range flagged {
+			fmt.Fprintf(tw, "  %s\t%s\n", formatIssues(link.RedirectIssues), formatHops(link.Redirects))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if result.InaccessibleLinks == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "\nInaccessible links:")
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, link := range result.Links {
+		if link.Accessible || !link.Class.Broken() {
+			continue
+		}
+		status := "-"
+		if link.Status != 0 {
+			status = fmt.Sprint(link.Status)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", status, link.ErrorKind, link.URL, link.Explanation)
+	}
+	return tw.Flush()
+}
+
+// formatHops renders a redirect chain as "301 url -> 200 url"
+func formatHops(hops []helper.Hop) string {
+	parts := make([]string, len(hops))
+	for i, hop := range hops {
+		parts[i] = hop.URL
+		if hop.Status != 0 {
+			parts[i] = fmt.Sprintf("%d %s", hop.Status, hop.URL)
+		}
+	}
+	return strings.Join(parts, " -> ")
+}
+
+func formatIssues(issues []helper.RedirectIssue) string {
+	parts := make([]string, len(issues))
+	for i, issue := range issues {
+		parts[i] = string(issue)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
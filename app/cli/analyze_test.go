@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/helper"
+)
+
+func TestAnalyze_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	if code := Analyze(context.Background(), nil, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("expected exit code %d without a URL, got %d", ExitUsage, code)
+	}
+	if code := Analyze(context.Background(), []string{"https://example.com", "--format", "xml"}, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("expected exit code %d for unknown format, got %d", ExitUsage, code)
+	}
+}
+
+func TestAnalyze_FailedPageJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := Analyze(context.Background(), []string{"http://192.168.1.1", "--format", "json"}, &stdout, &stderr)
+	if code != ExitFailed {
+		t.Errorf("expected exit code %d, got %d", ExitFailed, code)
+	}
+
+	var result analyzer.PageAnalysis
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", stdout.String(), err)
+	}
+	if !strings.Contains(result.Error.Message, "access to private network denied") {
+		t.Errorf("expected private network error, got: %s", result.Error.Message)
+	}
+}
+
+func TestAnalyze_FailedPageText(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := Analyze(context.Background(), []string{"ftp://example.com"}, &stdout, &stderr)
+	if code != ExitFailed {
+		t.Errorf("expected exit code %d, got %d", ExitFailed, code)
+	}
+	if !strings.Contains(stdout.String(), "unsupported URL scheme") {
+		t.Errorf("expected error in text output, got %q", stdout.String())
+	}
+}
+
+func TestAnalyze_Config(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	data := `Local:
+  HostPolicy:
+    Deny: ["8.8.8.0/24"]
+`
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := Analyze(context.Background(), []string{"http://8.8.8.8", "--config", file}, &stdout, &stderr)
+	if code != ExitFailed {
+		t.Errorf("expected exit code %d, got %d", ExitFailed, code)
+	}
+	if !strings.Contains(stdout.String(), "refused by policy") {
+		t.Errorf("expected the configured host policy to refuse the page, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	code = Analyze(context.Background(), []string{"http://8.8.8.8", "--config", filepath.Join(t.TempDir(), "missing.yaml")}, &stdout, &stderr)
+	if code != ExitFailed || stdout.Len() != 0 {
+		t.Errorf("expected exit code %d without output for a missing file, got %d and %q", ExitFailed, code, stdout.String())
+	}
+}
+
+func TestParseArgs_Interspersed(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", formatText, "")
+
+	positional, err := parseArgs(fs, []string{"https://a.example", "--format", "json", "https://b.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *format != formatJSON {
+		t.Errorf("expected format json, got %s", *format)
+	}
+	if want := []string{"https://a.example", "https://b.example"}; !reflect.DeepEqual(positional, want) {
+		t.Errorf("expected positional %v, got %v", want, positional)
+	}
+}
+
+func TestWriteText_BrokenLinks(t *testing.T) {
+	var out bytes.Buffer
+	result := analyzer.PageAnalysis{
+		URL:               "https://example.com",
+		Title:             "Example",
+		HTMLVersion:       "HTML5",
+		HeadingsCount:     map[string]int{"h1": 1},
+		InaccessibleLinks: 1,
+		LinkClasses:       map[analyzer.LinkClass]int{analyzer.ClassOK: 1, analyzer.ClassClientError: 1, analyzer.ClassBotProtected: 1},
+		Links: []analyzer.LinkReport{
+			{URL: "https://example.com/ok", Accessible: true, Status: 200, Class: analyzer.ClassOK},
+			{URL: "https://example.com/missing", Status: 404, ErrorKind: "client_error", Class: analyzer.ClassClientError},
+			{URL: "https://example.com/protected", Status: 403, ErrorKind: "bot_protection", Class: analyzer.ClassBotProtected},
+		},
+	}
+
+	if err := writeText(&out, result); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "h1=1") {
+		t.Errorf("expected heading counts, got %q", out.String())
+	}
+	if !strings.Contains(out.String(), "https://example.com/missing") || strings.Contains(out.String(), "https://example.com/ok") ||
+		strings.Contains(out.String(), "https://example.com/protected") {
+		t.Errorf("expected only the inaccessible link to be listed, got %q", out.String())
+	}
+	if !strings.Contains(out.String(), "ok=1 bot_protected=1 client_error=1") {
+		t.Errorf("expected link classes, got %q", out.String())
+	}
+}
+
+func TestWriteText_Redirects(t *testing.T) {
+	var out bytes.Buffer
+	result := analyzer.PageAnalysis{
+		URL:       "http://example.com",
+		Redirects: []helper.Hop{{URL: "http://example.com", Status: 301}, {URL: "https://example.com", Status: 200}},
+		Links: []analyzer.LinkReport{
+			{URL: "https://example.com/old", Accessible: true, Status: 200, Class: analyzer.ClassRedirected,
+				Redirects:      []helper.Hop{{URL: "https://example.com/old", Status: 301}, {URL: "http://example.com/new", Status: 200}},
+				RedirectIssues: []helper.RedirectIssue{helper.RedirectDowngrade}},
+		},
+	}
+
+	if err := writeText(&out, result); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "301 http://example.com -> 200 https://example.com") {
+		t.Errorf("expected the page redirect chain, got %q", out.String())
+	}
+	if !strings.Contains(out.String(), "https_downgrade") {
+		t.Errorf("expected the flagged link, got %q", out.String())
+	}
+}
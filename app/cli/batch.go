@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+)
+
+// Batch runs `page-insight-tool batch [--file path] [url ...]` and returns the process exit code.
+// Cancelling ctx aborts the analyses still running.
+func Batch(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := newFlagSet("batch", stderr)
+	file := fs.String("file", "", "read URLs from this file, or from stdin with -")
+	inputFormat := fs.String("input-format", "auto", "format of --file: auto, text, csv or json")
+	format := fs.String("format", formatText, "output format: text or json")
+	concurrency := fs.Int("concurrency", analyzer.DefaultBatchConcurrency, "number of pages analyzed in parallel")
+	maxBroken := fs.Int("max-broken", -1, "fail when more links than this are inaccessible in total (-1 disables the check)")
+	timeout := fs.Duration("timeout", 0, "abort the whole batch after this duration (0 means no limit)")
+	configFile := configFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: page-insight-tool batch [--file path|-] [--format text|json] [--concurrency N] [--max-broken N] [--timeout D] [--config path] [url ...]")
+		fs.PrintDefaults()
+	}
+
+	urls, err := parseArgs(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if *format != formatText && *format != formatJSON {
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return ExitUsage
+	}
+	if *concurrency < 1 {
+		fmt.Fprintln(stderr, "concurrency must be at least 1")
+		return ExitUsage
+	}
+
+	if *file != "" {
+		listed, err := readURLFile(*file, *inputFormat, stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return ExitUsage
+		}
+		urls = append(urls, listed...)
+	}
+	if len(urls) == 0 {
+		fs.Usage()
+		return ExitUsage
+	}
+
+	a, err := newAnalyzer(*configFile)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailed
+	}
+
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
+
+	report := a.AnalyzeBatch(ctx, urls, *concurrency)
+
+	if *format == formatJSON {
+		err = writeJSON(stdout, report)
+	} else {
+		err = writeBatchText(stdout, report)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailed
+	}
+
+	if report.Summary.Failed > 0 {
+		return ExitFailed
+	}
+	if *maxBroken >= 0 && report.Summary.BrokenLinks > *maxBroken {
+		fmt.Fprintf(stderr, "%d inaccessible links exceed the threshold of %d\n", report.Summary.BrokenLinks, *maxBroken)
+		return ExitThreshold
+	}
+	return ExitOK
+}
+
+// readURLFile reads a URL list, guessing its format from the extension when asked to
+func readURLFile(path, format string, stdin io.Reader) ([]string, error) {
+	if format == "auto" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			format = analyzer.FormatCSV
+		case ".json":
+			format = analyzer.FormatJSON
+		default:
+			format = analyzer.FormatText
+		}
+	}
+
+	if path == "-" {
+		return analyzer.ParseURLList(stdin, format)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return analyzer.ParseURLList(f, format)
+}
+
+func writeBatchText(w io.Writer, report analyzer.BatchReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "URL\tRESULT\tTITLE\tBROKEN LINKS\tLOGIN FORM")
+	for _, result := range report.Results {
+		if result.Error.Message != "" {
+			fmt.Fprintf(tw, "%s\tERROR\t%s\t-\t-\n", result.URL, result.Error.Message)
+			continue
+		}
+		title := result.Title
+		if title == "" {
+			title = "(missing)"
+		}
+		fmt.Fprintf(tw, "%s\tOK\t%s\t%d\t%s\n", result.URL, title, result.InaccessibleLinks, yesNo(result.HasLoginForm))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	s := report.Summary
+	_, err := fmt.Fprintf(w, "\n%d URLs: %d succeeded, %d failed, %d broken links, %d missing titles, %d login forms\n",
+		s.URLs, s.Succeeded, s.Failed, s.BrokenLinks, s.MissingTitles, s.LoginForms)
+	return err
+}
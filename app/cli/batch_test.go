@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+)
+
+func TestBatch_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	if code := Batch(context.Background(), nil, strings.NewReader(""), &stdout, &stderr); code != ExitUsage {
+		t.Errorf("expected exit code %d without URLs, got %d", ExitUsage, code)
+	}
+	if code := Batch(context.Background(), []string{"--file", "/non/existent/urls.txt"}, strings.NewReader(""), &stdout, &stderr); code != ExitUsage {
+		t.Errorf("expected exit code %d for a missing file, got %d", ExitUsage, code)
+	}
+}
+
+func TestBatch_FileAndArgs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.csv")
+	if err := os.WriteFile(path, []byte("url\nhttp://10.0.0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := Batch(context.Background(), []string{"http://192.168.1.1", "--file", path, "--format", "json"}, strings.NewReader(""), &stdout, &stderr)
+	if code != ExitFailed {
+		t.Errorf("expected exit code %d, got %d", ExitFailed, code)
+	}
+
+	var report analyzer.BatchReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", stdout.String(), err)
+	}
+	if report.Summary.URLs != 2 {
+		t.Errorf("expected 2 URLs in the report, got %d", report.Summary.URLs)
+	}
+}
+
+func TestBatch_Stdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := Batch(context.Background(), []string{"--file", "-"}, strings.NewReader("http://10.0.0.1\nhttp://10.0.0.2\n"), &stdout, &stderr)
+	if code != ExitFailed {
+		t.Errorf("expected exit code %d, got %d", ExitFailed, code)
+	}
+	if !strings.Contains(stdout.String(), "2 URLs: 0 succeeded, 2 failed") {
+		t.Errorf("expected summary line, got %q", stdout.String())
+	}
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/rabie/page-insight-tool/app/config"
+)
+
+// DefaultConfigFile is the configuration checked by `config validate` when none is given
+const DefaultConfigFile = "app/config/page-insight-tool.yaml"
+
+// Config runs `page-insight-tool config validate [--config path] [--env name]` and
+// returns the process exit code. Every environment of the file is checked unless
+// one is named; environment variables are not applied.
+func Config(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(stderr, "Usage: page-insight-tool config validate [--config path] [--env name]")
+		return ExitUsage
+	}
+
+	fs := newFlagSet("config validate", stderr)
+	file := fs.String("config", DefaultConfigFile, "configuration file to check")
+	env := fs.String("env", "", "environment to check (default: all)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: page-insight-tool config validate [--config path] [--env name]")
+		fs.PrintDefaults()
+	}
+	positional, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return ExitUsage
+	}
+	if len(positional) > 0 {
+		fs.Usage()
+		return ExitUsage
+	}
+
+	envs := []string{*env}
+	if *env == "" {
+		if envs, err = config.Environments(*file); err != nil {
+			fmt.Fprintln(stderr, err)
+			return ExitFailed
+		}
+	}
+
+	code := ExitOK
+	for _, name := range envs {
+		if _, err := config.LoadEnvironment(*file, name); err != nil {
+			fmt.Fprintf(stderr, "%s: invalid\n%v\n", name, err)
+			code = ExitFailed
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: ok\n", name)
+	}
+	return code
+}
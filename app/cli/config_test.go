@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	data := `Local:
+  Port: "8080"
+Production:
+  Port: "80"
+  MaxRedirects: -1
+`
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := Config([]string{"validate", "--config", file}, &stdout, &stderr); code != ExitFailed {
+		t.Errorf("expected exit code %d, got %d", ExitFailed, code)
+	}
+	if stdout.String() != "Local: ok\n" {
+		t.Errorf("expected the valid environment to be reported, got %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "Production: invalid") || !strings.Contains(stderr.String(), file+":5: MaxRedirects") {
+		t.Errorf("expected the invalid setting with its line, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	if code := Config([]string{"validate", "--config", file, "--env", "Local"}, &stdout, &stderr); code != ExitOK {
+		t.Errorf("expected exit code %d for a valid environment, got %d", ExitOK, code)
+	}
+}
+
+func TestConfig_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := Config(nil, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("expected exit code %d without a subcommand, got %d", ExitUsage, code)
+	}
+	if code := Config([]string{"validate", "--config", "/non/existent.yaml"}, &stdout, &stderr); code != ExitFailed {
+		t.Errorf("expected exit code %d for a missing file, got %d", ExitFailed, code)
+	}
+}
@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+)
+
+// Diff runs `page-insight-tool diff <before> <after>` and returns the process exit code.
+// Each side is a URL, analyzed now, or a JSON file holding an analysis as written by
+// `analyze --format json` or a stored record from /api/v1/results/{id}.
+func Diff(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("diff", stderr)
+	format := fs.String("format", formatText, "output format: text or json")
+	failOnRegression := fs.Bool("fail-on-regression", false, "fail when links broke, headings were lost or the page now fails")
+	timeout := fs.Duration("timeout", 0, "abort the analyses after this duration (0 means no limit)")
+	configFile := configFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: page-insight-tool diff <before> <after> [--format text|json] [--fail-on-regression] [--timeout D] [--config path]")
+		fmt.Fprintln(stderr, "Each of before and after is a URL or a JSON file of an analysis.")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(positional) != 2 {
+		fs.Usage()
+		return ExitUsage
+	}
+	if *format != formatText && *format != formatJSON {
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return ExitUsage
+	}
+
+	ctx, cancel := withTimeout(ctx, *timeout)
+	defer cancel()
+
+	// Both sides are loaded side by side; the analyzer is only started for URLs
+	var a *analyzer.Analyzer
+	if strings.Contains(positional[0], "://") || strings.Contains(positional[1], "://") {
+		if a, err = newAnalyzer(*configFile); err != nil {
+			fmt.Fprintln(stderr, err)
+			return ExitFailed
+		}
+	}
+	analyses := make([]analyzer.PageAnalysis, 2)
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i, ref := range positional {
+		wg.Add(1)
+		go func(i int, ref string) {
+			defer wg.Done()
+			if !strings.Contains(ref, "://") {
+				analyses[i], errs[i] = readAnalysis(ref)
+				return
+			}
+			analyses[i] = a.AnalyzePage(ctx, ref)
+			if analyses[i].Refused() {
+				errs[i] = fmt.Errorf("%s: %s", ref, analyses[i].Error.Message)
+			}
+		}(i, ref)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return ExitFailed
+		}
+	}
+
+	d := analyzer.Compare(analyses[0], analyses[1])
+	if *format == formatJSON {
+		err = writeJSON(stdout, d)
+	} else {
+		err = writeDiffText(stdout, d)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailed
+	}
+
+	if *failOnRegression && d.Regressed {
+		fmt.Fprintln(stderr, "the after analysis regressed")
+		return ExitThreshold
+	}
+	return ExitOK
+}
+
+// readAnalysis reads an analysis from a JSON file, either bare or wrapped in a history record
+func readAnalysis(path string) (analyzer.PageAnalysis, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return analyzer.PageAnalysis{}, err
+	}
+	var rec struct {
+		Analysis *analyzer.PageAnalysis `json:"analysis"`
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return analyzer.PageAnalysis{}, fmt.Errorf("%s: %v", path, err)
+	}
+	if rec.Analysis != nil {
+		return *rec.Analysis, nil
+	}
+	var a analyzer.PageAnalysis
+	if err := json.Unmarshal(data, &a); err != nil {
+		return analyzer.PageAnalysis{}, fmt.Errorf("%s: %v", path, err)
+	}
+	if a.URL == "" {
+		return analyzer.PageAnalysis{}, fmt.Errorf("%s: not an analysis", path)
+	}
+	return a, nil
+}
+
+func writeDiffText(w io.Writer, d analyzer.Diff) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Before:\t%s\n", d.Before.URL)
+	fmt.Fprintf(tw, "After:\t%s\n", d.After.URL)
+	if !d.Changed {
+		fmt.Fprintln(tw, "Changes:\tnone")
+		return tw.Flush()
+	}
+	if d.Error != nil {
+		fmt.Fprintf(tw, "Error:\t%s -> %s\n", orNone(d.Error.Before.Message), orNone(d.Error.After.Message))
+	}
+	if d.Title != nil {
+		fmt.Fprintf(tw, "Title:\t%q -> %q\n", d.Title.Before, d.Title.After)
+	}
+	if d.HTMLVersion != nil {
+		fmt.Fprintf(tw, "HTML version:\t%s -> %s\n", d.HTMLVersion.Before, d.HTMLVersion.After)
+	}
+	if len(d.Headings) > 0 {
+		parts := make([]string, len(d.Headings))
+		for i, h := range d.Headings {
+			parts[i] = fmt.Sprintf("%s %d -> %d", h.Level, h.Before, h.After)
+		}
+		fmt.Fprintf(tw, "Headings:\t%s\n", strings.Join(parts, ", "))
+	}
+	if d.LoginForm != nil {
+		fmt.Fprintf(tw, "Login form:\t%s -> %s\n", yesNo(d.LoginForm.Before), yesNo(d.LoginForm.After))
+	}
+	fmt.Fprintf(tw, "Regressed:\t%s\n", yesNo(d.Regressed))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, section := range []struct {
+		title string
+		links []analyzer.LinkReport
+	}{
+		{"Newly broken links:", d.NewlyBroken},
+		{"Newly fixed links:", d.NewlyFixed},
+	} {
+		if len(section.links) == 0 {
+			continue
+		}
+		fmt.Fprintln(w, "\n"+section.title)
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, link := range section.links {
+			status := "-"
+			if link.Status != 0 {
+				status = fmt.Sprint(link.Status)
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", status, link.Class, link.URL)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+)
+
+// writeAnalysis writes v as JSON to a file of dir and returns its path
+func writeAnalysis(t *testing.T, dir, name string, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiff_Files(t *testing.T) {
+	dir := t.TempDir()
+	before := writeAnalysis(t, dir, "before.json", analyzer.PageAnalysis{
+		URL:           "https://example.com",
+		Title:         "Example",
+		HTMLVersion:   "HTML5",
+		HeadingsCount: map[string]int{"h1": 1},
+		Links:         []analyzer.LinkReport{{URL: "https://example.com/docs", Internal: true, Accessible: true, Class: analyzer.ClassOK}},
+	})
+	// A stored record, as served by /api/v1/results/{id}
+	after := writeAnalysis(t, dir, "after.json", map[string]interface{}{
+		"id": "9f1c4e2a7b3d5e6f80a1b2c3",
+		"analysis": analyzer.PageAnalysis{
+			URL:          "https://example.com",
+			Title:        "Example",
+			HTMLVersion:  "HTML5",
+			HasLoginForm: true,
+			Links:        []analyzer.LinkReport{{URL: "https://example.com/docs", Internal: true, Status: 404, Class: analyzer.ClassClientError}},
+		},
+	})
+
+	var stdout, stderr bytes.Buffer
+	if code := Diff(context.Background(), []string{before, after}, &stdout, &stderr); code != ExitOK {
+		t.Fatalf("expected exit code %d, got %d: %s", ExitOK, code, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{"Headings:", "h1 1 -> 0", "Login form:", "no -> yes", "Newly broken links:", "404", "https://example.com/docs"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in the output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Title:") {
+		t.Errorf("expected the unchanged title to be left out:\n%s", out)
+	}
+
+	stdout.Reset()
+	code := Diff(context.Background(), []string{"--format", "json", "--fail-on-regression", before, after}, &stdout, &stderr)
+	if code != ExitThreshold {
+		t.Errorf("expected exit code %d on regression, got %d", ExitThreshold, code)
+	}
+	var d analyzer.Diff
+	if err := json.Unmarshal(stdout.Bytes(), &d); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", stdout.String(), err)
+	}
+	if !d.Regressed || len(d.NewlyBroken) != 1 {
+		t.Errorf("unexpected diff %+v", d)
+	}
+
+	stdout.Reset()
+	if code := Diff(context.Background(), []string{before, before}, &stdout, &stderr); code != ExitOK || !strings.Contains(stdout.String(), "none") {
+		t.Errorf("expected no changes, got %d:\n%s", code, stdout.String())
+	}
+}
+
+func TestDiff_Errors(t *testing.T) {
+	dir := t.TempDir()
+	valid := writeAnalysis(t, dir, "valid.json", analyzer.PageAnalysis{URL: "https://example.com"})
+	notAnalysis := writeAnalysis(t, dir, "other.json", map[string]int{"count": 1})
+
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{valid}, ExitUsage},
+		{[]string{valid, valid, "--format", "xml"}, ExitUsage},
+		{[]string{valid, filepath.Join(dir, "missing.json")}, ExitFailed},
+		{[]string{valid, notAnalysis}, ExitFailed},
+		{[]string{"http://192.168.1.1", valid}, ExitFailed},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := Diff(context.Background(), tt.args, &stdout, &stderr); code != tt.code {
+			t.Errorf("%v: expected exit code %d, got %d (%s)", tt.args, tt.code, code, stderr.String())
+		}
+	}
+}
@@ -1,41 +1,258 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
-	"log"
-	"net/http"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/checker"
+	"github.com/rabie/page-insight-tool/app/cli"
 	"github.com/rabie/page-insight-tool/app/config"
+	"github.com/rabie/page-insight-tool/app/handlers"
+	"github.com/rabie/page-insight-tool/app/history"
+	"github.com/rabie/page-insight-tool/app/jobs"
+	"github.com/rabie/page-insight-tool/app/logging"
+	"github.com/rabie/page-insight-tool/app/metrics"
+	"github.com/rabie/page-insight-tool/app/monitor"
 	"github.com/rabie/page-insight-tool/app/router"
+	"github.com/rabie/page-insight-tool/app/rule"
+	"github.com/rabie/page-insight-tool/app/server"
 )
 
 func main() {
+	// Subcommands run without starting the web server; their output is the report,
+	// so only warnings and errors are logged
+	if len(os.Args) > 1 {
+		slog.SetDefault(logging.New(os.Stderr, slog.LevelWarn))
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		switch os.Args[1] {
+		case "analyze":
+			code := cli.Analyze(ctx, os.Args[2:], os.Stdout, os.Stderr)
+			stop()
+			os.Exit(code)
+		case "batch":
+			code := cli.Batch(ctx, os.Args[2:], os.Stdin, os.Stdout, os.Stderr)
+			stop()
+			os.Exit(code)
+		case "diff":
+			code := cli.Diff(ctx, os.Args[2:], os.Stdout, os.Stderr)
+			stop()
+			os.Exit(code)
+		case "config":
+			code := cli.Config(os.Args[2:], os.Stdout, os.Stderr)
+			stop()
+			os.Exit(code)
+		}
+		stop()
+	}
+
 	var configFile string
 	var debug bool
+	var logLevel slog.Level
+	var reloadInterval time.Duration
 	flag.StringVar(&configFile, "config", "", "config file location + name")
-	flag.BoolVar(&debug, "debug", false, "debug log level (default Info)")
+	flag.BoolVar(&debug, "debug", false, "debug log level, same as -log-level debug")
+	flag.TextVar(&logLevel, "log-level", slog.LevelInfo, "minimum level logged: debug, info, warn or error")
+	flag.DurationVar(&reloadInterval, "reload-interval", config.DefaultReloadInterval, "how often the config file is checked for changes, 0 to only reload on SIGHUP")
+	overrides := config.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
-	// Set up logging
-	if debug {
-		log.SetFlags(log.LstdFlags | log.Lshortfile)
-	} else {
-		log.SetFlags(log.LstdFlags)
+	// Log JSON lines to stderr; DEBUG=true also enables the debug level
+	if envDebug, _ := strconv.ParseBool(os.Getenv("DEBUG")); debug || envDebug {
+		logLevel = slog.LevelDebug
 	}
+	slog.SetDefault(logging.New(os.Stderr, logLevel))
 
-	// Load configuration
-	cfg := config.LoadConfig(configFile)
-	if cfg == nil {
-		log.Fatal("Failed to load configuration")
+	// Load configuration; command line flags win over the file on every reload
+	loadConfig := func() (*config.Config, error) {
+		cfg, err := config.LoadConfig(configFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := overrides.Apply(cfg); err != nil {
+			return nil, err
+		}
+		return cfg, nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		fatal("failed to load configuration", err)
+	}
+	opts, err := cli.AnalyzerOptions(cfg)
+	if err != nil {
+		fatal("invalid analysis options", err)
 	}
 
-	// Create router
-	r := router.New()
+	// SIGTERM and SIGINT stop the server gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Start server
-	log.Printf("Page Insight Tool listening on: %s", cfg.ServerAddress)
-	err := http.ListenAndServe(cfg.ServerAddress, r)
+	// Start the shared link checker and background analysis workers
+	pool := checker.NewPool(cfg.LinkCheckWorkers)
+	defer pool.Close()
+	pool.SetHostLimits(hostLimits(cfg.HostLimits))
+	pool.RegisterMetrics(metrics.Default)
+	linkCache := checker.NewCache(cli.CacheOptions(cfg))
+	pageAnalyzer := analyzer.New(pool, linkCache, opts)
+
+	// Keep past analyses on disk, or in memory without a history directory
+	store, err := openHistory(cfg.History)
 	if err != nil {
-		log.Fatal(err)
+		fatal("failed to open the history", err)
+	}
+	defer store.Close()
+	go pruneHistory(ctx, store)
+
+	jobManager := jobs.NewManager(jobs.DefaultOptions(), history.Recorded(store, pageAnalyzer.AnalyzePage))
+	defer jobManager.Close()
+
+	// Analyze the monitored pages on their schedule, alerting the webhooks on regressions
+	scheduler := monitor.NewScheduler(store, pageAnalyzer.AnalyzePage)
+	if err := setMonitors(scheduler, cfg.Monitoring); err != nil {
+		fatal("invalid monitors", err)
+	}
+	scheduler.SetNotifiers(notifiers(cfg.Monitoring)...)
+	monitorsDone := make(chan struct{})
+	go func() {
+		defer close(monitorsDone)
+		scheduler.Run(ctx)
+	}()
+	// The runs in flight save to the history, closed after them
+	defer func() {
+		stop()
+		<-monitorsDone
+	}()
+
+	// Reload the configuration when its file changes or on SIGHUP
+	reloader := config.NewReloader(configFile, cfg, loadConfig, func(cfg *config.Config) error {
+		opts, err := cli.AnalyzerOptions(cfg)
+		if err != nil {
+			return err
+		}
+		if err := setMonitors(scheduler, cfg.Monitoring); err != nil {
+			return err
+		}
+		scheduler.SetNotifiers(notifiers(cfg.Monitoring)...)
+		pool.SetWorkers(cfg.LinkCheckWorkers)
+		pool.SetHostLimits(hostLimits(cfg.HostLimits))
+		linkCache.SetOptions(cli.CacheOptions(cfg))
+		pageAnalyzer.SetOptions(opts)
+		return nil
+	})
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go reloader.Watch(ctx, reloadInterval, hangup)
+
+	// Create router
+	h := handlers.New(pageAnalyzer, pool, jobManager)
+	h.SetConfig(reloader.Current)
+	h.SetHistory(store)
+	h.SetMonitors(scheduler)
+	r := router.New(h)
+
+	// Start server; on shutdown the queued analysis jobs are drained with the requests
+	srv := server.New(server.Options{
+		Addr:              cfg.ServerAddress,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+		ShutdownTimeout:   cfg.Server.ShutdownTimeout,
+		TLSCertFile:       cfg.Server.TLSCertFile,
+		TLSKeyFile:        cfg.Server.TLSKeyFile,
+		Drain:             jobManager.Shutdown,
+	}, r)
+	scheme := "http"
+	if srv.TLS() {
+		scheme = "https"
+	}
+	slog.Info("Page Insight Tool listening", "address", scheme+"://"+cfg.ServerAddress)
+	// Missing the shutdown deadline is logged by the server and not a failure
+	if err := srv.Run(ctx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		fatal("server failed", err)
+	}
+	slog.Info("server stopped")
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// openHistory opens the history store configured by cfg
+func openHistory(cfg config.History) (history.Store, error) {
+	retention := history.Retention{MaxAge: cfg.MaxAge, MaxEntries: cfg.MaxEntries}
+	if cfg.Dir == "" {
+		return history.NewMemoryStore(retention), nil
+	}
+	return history.OpenDiskStore(cfg.Dir, retention)
+}
+
+// pruneHistory drops the analyses past the retention every hour until ctx is done
+func pruneHistory(ctx context.Context, store history.Store) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if n, err := store.Prune(ctx, now); err != nil {
+				slog.Warn("failed to prune the history", "error", err)
+			} else if n > 0 {
+				slog.Info("history pruned", "dropped", n)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// setMonitors hands the monitors configured by cfg to scheduler
+func setMonitors(scheduler *monitor.Scheduler, cfg config.Monitoring) error {
+	monitors := make([]monitor.Monitor, 0, len(cfg.Monitors))
+	for _, m := range cfg.Monitors {
+		var rules []rule.Rule
+		for _, name := range m.Rules {
+			r, err := rule.Parse(name)
+			if err != nil {
+				return fmt.Errorf("monitor %q: %w", m.Name, err)
+			}
+			rules = append(rules, r)
+		}
+		monitors = append(monitors, monitor.Monitor{Name: m.Name, URL: m.URL, Schedule: m.Schedule, Rules: rules})
+	}
+	return scheduler.SetMonitors(monitors)
+}
+
+// notifiers returns the notifiers the alerts are delivered to
+func notifiers(cfg config.Monitoring) []monitor.Notifier {
+	var out []monitor.Notifier
+	for _, w := range cfg.Webhooks {
+		out = append(out, monitor.NewWebhook(w.URL, w.Timeout))
+	}
+	return out
+}
+
+// hostLimits turns the configured politeness rules into checker limits.
+// A "*" rule replaces the default applied to the hosts matching no other rule.
+func hostLimits(rules []config.HostLimit) checker.HostLimits {
+	limits := checker.DefaultHostLimits()
+	for _, r := range rules {
+		rule := checker.HostRule{Pattern: r.Pattern, MaxConcurrent: r.MaxConcurrent, Interval: r.Interval}
+		if r.Pattern == "*" {
+			limits.Default = rule
+			continue
+		}
+		limits.Rules = append(limits.Rules, rule)
 	}
+	return limits
 }
@@ -1,70 +1,409 @@
 package config
 
 import (
-	"gopkg.in/yaml.v2"
+	"errors"
+	"fmt"
+	"net"
 	"os"
-	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
 )
 
+// Defaults applied by LoadConfig to the settings left unset
+const (
+	DefaultPort        = "8080"
+	DefaultEnvironment = "Local"
+	// DefaultLinkCheckWorkers is the global number of concurrent outbound link checks
+	DefaultLinkCheckWorkers = 20
+	DefaultPageTimeout      = 30 * time.Second
+	DefaultLinkTimeout      = 10 * time.Second
+	DefaultUserAgent        = "Page-Insight-Tool/1.0"
+	DefaultMaxLinks         = 500
+	DefaultMaxRedirects     = 5
+)
+
+// Server defaults; WriteTimeout leaves room for an analysis to finish
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 2 * time.Minute
+	DefaultIdleTimeout       = 2 * time.Minute
+	DefaultMaxHeaderBytes    = 1 << 20
+	DefaultShutdownTimeout   = 30 * time.Second
+)
+
+// History retention defaults
+const (
+	DefaultHistoryMaxAge     = 30 * 24 * time.Hour
+	DefaultHistoryMaxEntries = 10000
+)
+
+// HostLimit is the politeness rule applied to link checks against the hosts matching Pattern:
+// an exact host, a wildcard suffix such as "*.example.com", or "*" for every other host
+type HostLimit struct {
+	Pattern       string        `yaml:"Pattern"`
+	MaxConcurrent int           `yaml:"MaxConcurrent"`
+	Interval      time.Duration `yaml:"Interval"`
+}
+
+// LinkCache bounds the cache of link check results shared by every analysis
+type LinkCache struct {
+	TTL         time.Duration `yaml:"TTL"`
+	NegativeTTL time.Duration `yaml:"NegativeTTL"`
+	MaxEntries  int           `yaml:"MaxEntries"`
+}
+
+// HostPolicy restricts the hosts that may be analyzed. Rules are exact hosts, wildcard
+// suffixes such as "*.example.com" or CIDRs; deny rules win over allow rules, and when
+// allow rules are set a host must match one of them.
+type HostPolicy struct {
+	Allow        []string `yaml:"Allow"`
+	Deny         []string `yaml:"Deny"`
+	ApplyToLinks bool     `yaml:"ApplyToLinks"` // also refuse links to hosts out of policy
+}
+
+// Server configures the HTTP server. HTTPS is served when both TLS files are set.
+type Server struct {
+	ReadHeaderTimeout time.Duration `yaml:"ReadHeaderTimeout"`
+	ReadTimeout       time.Duration `yaml:"ReadTimeout"`
+	WriteTimeout      time.Duration `yaml:"WriteTimeout"`
+	IdleTimeout       time.Duration `yaml:"IdleTimeout"`
+	MaxHeaderBytes    int           `yaml:"MaxHeaderBytes"`
+	ShutdownTimeout   time.Duration `yaml:"ShutdownTimeout"` // drain deadline on SIGTERM or SIGINT
+	TLSCertFile       string        `yaml:"TLSCertFile"`
+	TLSKeyFile        string        `yaml:"TLSKeyFile"`
+}
+
+// History configures where past analyses are kept and for how long. They are kept
+// in memory, and lost on restart, when Dir is empty.
+type History struct {
+	Dir        string        `yaml:"Dir"`
+	MaxAge     time.Duration `yaml:"MaxAge"`
+	MaxEntries int           `yaml:"MaxEntries"`
+}
+
+// Monitoring configures the pages analyzed on a schedule and the webhooks their
+// alerts are posted to
+type Monitoring struct {
+	Monitors []Monitor `yaml:"Monitors"`
+	Webhooks []Webhook `yaml:"Webhooks"`
+}
+
+// Monitor is a page analyzed on Schedule, a cron expression or "@every <duration>",
+// raising an alert when it trips one of Rules, or any rule when none is given
+type Monitor struct {
+	Name     string   `yaml:"Name"`
+	URL      string   `yaml:"URL"`
+	Schedule string   `yaml:"Schedule"`
+	Rules    []string `yaml:"Rules"`
+}
+
+// Webhook receives every alert as a JSON POST, each bounded by Timeout or the
+// notifier's default when unset
+type Webhook struct {
+	URL     string        `yaml:"URL"`
+	Timeout time.Duration `yaml:"Timeout"`
+}
+
 // Environment represents environment-specific configuration
 type Environment struct {
-	Port string `yaml:"Port"`
+	Host             string        `yaml:"Host"`
+	Port             string        `yaml:"Port"`
+	PageTimeout      time.Duration `yaml:"PageTimeout"`
+	LinkTimeout      time.Duration `yaml:"LinkTimeout"`
+	UserAgent        string        `yaml:"UserAgent"`
+	MaxLinks         int           `yaml:"MaxLinks"`
+	LinkCheckWorkers int           `yaml:"LinkCheckWorkers"`
+	HostLimits       []HostLimit   `yaml:"HostLimits"`
+	LinkCache        LinkCache     `yaml:"LinkCache"`
+	MaxRedirects     int           `yaml:"MaxRedirects"`
+	DeniedNetworks   []string      `yaml:"DeniedNetworks"`
+	HostPolicy       HostPolicy    `yaml:"HostPolicy"`
+	Server           Server        `yaml:"Server"`
+	History          History       `yaml:"History"`
+	Monitoring       Monitoring    `yaml:"Monitoring"`
 }
 
 // Config represents the application configuration
 type Config struct {
-	ServerAddress string
+	ServerAddress    string // Host and Port joined
+	Host             string // interface to listen on, every interface when empty
+	Port             string
+	PageTimeout      time.Duration // bounds fetching the analyzed page
+	LinkTimeout      time.Duration // bounds checking one link
+	UserAgent        string
+	MaxLinks         int // links checked per page
+	LinkCheckWorkers int
+	HostLimits       []HostLimit
+	LinkCache        LinkCache
+	MaxRedirects     int
+	DeniedNetworks   []string // CIDRs blocked on top of the reserved ranges
+	HostPolicy       HostPolicy
+	Server           Server
+	History          History
+	Monitoring       Monitoring
+
+	// origins tells where each setting was given, to locate invalid values
+	origins map[string]string
 }
 
-var envs map[string]Environment
+// LoadConfig loads the APP_ENV environment (Local by default) of the YAML file, when
+// one is given, then applies the environment variables. It fails on a missing or
+// malformed file, an unknown environment or key, and invalid settings.
+func LoadConfig(configFile string) (*Config, error) {
+	cfg := &Config{}
+	if configFile != "" {
+		env := DefaultEnvironment
+		if appEnv := os.Getenv("APP_ENV"); appEnv != "" {
+			env = appEnv
+		}
+		if err := cfg.loadYaml(configFile, env); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.finish(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
 
-// LoadConfig loads configuration from YAML file or environment variables
-func LoadConfig(configFile string) *Config {
-	cfg := &Config{
-		ServerAddress: ":8080",
+// LoadEnvironment loads the env environment of the YAML file alone, without the
+// environment variables, to check a file before it is deployed
+func LoadEnvironment(configFile, env string) (*Config, error) {
+	cfg := &Config{}
+	if err := cfg.loadYaml(configFile, env); err != nil {
+		return nil, err
+	}
+	if err := cfg.finish(); err != nil {
+		return nil, err
 	}
+	return cfg, nil
+}
 
-	// Load from YAML file if provided
-	if configFile != "" {
-		if yamlConfig := loadYamlConfig(configFile); yamlConfig != nil {
-			cfg = yamlConfig
-		}
+// Environments returns the sorted names of the environments defined in the YAML file
+func Environments(configFile string) ([]string, error) {
+	envs, _, err := readYaml(configFile)
+	if err != nil {
+		return nil, err
 	}
+	return environmentNames(envs), nil
+}
 
-	// Override with environment variables if available
-	if port := os.Getenv("PORT"); port != "" {
-		cfg.ServerAddress = ":" + port
+// finish applies the defaults, validates the result and derives ServerAddress
+func (c *Config) finish() error {
+	c.applyDefaults()
+	if err := c.Validate(); err != nil {
+		return err
 	}
+	c.ServerAddress = net.JoinHostPort(c.Host, c.Port)
+	return nil
+}
 
-	return cfg
+// applyDefaults sets the settings left unset. Invalid values are left for Validate to report.
+func (c *Config) applyDefaults() {
+	if c.Port == "" {
+		c.Port = DefaultPort
+	}
+	if c.PageTimeout == 0 {
+		c.PageTimeout = DefaultPageTimeout
+	}
+	if c.LinkTimeout == 0 {
+		c.LinkTimeout = DefaultLinkTimeout
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = DefaultUserAgent
+	}
+	if c.MaxLinks == 0 {
+		c.MaxLinks = DefaultMaxLinks
+	}
+	if c.LinkCheckWorkers == 0 {
+		c.LinkCheckWorkers = DefaultLinkCheckWorkers
+	}
+	if c.MaxRedirects == 0 {
+		c.MaxRedirects = DefaultMaxRedirects
+	}
+	if c.Server.ReadHeaderTimeout == 0 {
+		c.Server.ReadHeaderTimeout = DefaultReadHeaderTimeout
+	}
+	if c.Server.ReadTimeout == 0 {
+		c.Server.ReadTimeout = DefaultReadTimeout
+	}
+	if c.Server.WriteTimeout == 0 {
+		c.Server.WriteTimeout = DefaultWriteTimeout
+	}
+	if c.Server.IdleTimeout == 0 {
+		c.Server.IdleTimeout = DefaultIdleTimeout
+	}
+	if c.Server.MaxHeaderBytes == 0 {
+		c.Server.MaxHeaderBytes = DefaultMaxHeaderBytes
+	}
+	if c.Server.ShutdownTimeout == 0 {
+		c.Server.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if c.History.MaxAge == 0 {
+		c.History.MaxAge = DefaultHistoryMaxAge
+	}
+	if c.History.MaxEntries == 0 {
+		c.History.MaxEntries = DefaultHistoryMaxEntries
+	}
 }
 
-// loadYamlConfig loads configuration from YAML file
-func loadYamlConfig(filename string) *Config {
-	if filename == "" {
-		filename, _ = filepath.Abs("./app/config/page-insight-tool.yaml")
+// setOrigin records where field was given
+func (c *Config) setOrigin(field, origin string) {
+	if c.origins == nil {
+		c.origins = make(map[string]string)
 	}
+	c.origins[field] = origin
+}
 
-	yamlFile, err := os.ReadFile(filename)
+// readYaml parses the YAML file, rejecting unknown keys, and returns its raw content
+func readYaml(filename string) (map[string]Environment, []byte, error) {
+	data, err := os.ReadFile(filename)
 	if err != nil {
-		return nil
+		return nil, nil, err
 	}
+	var envs map[string]Environment
+	if err := yaml.UnmarshalStrict(data, &envs); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return envs, data, nil
+}
 
-	err = yaml.Unmarshal(yamlFile, &envs)
+// loadYaml loads the env environment of the YAML file
+func (c *Config) loadYaml(filename, env string) error {
+	envs, data, err := readYaml(filename)
 	if err != nil {
-		return nil
+		return err
+	}
+	e, ok := envs[env]
+	if !ok {
+		return fmt.Errorf("%s: unknown environment %q (defined: %s)", filename, env, strings.Join(environmentNames(envs), ", "))
 	}
 
-	// Default to Local environment
-	env := "Local"
-	// Override with environment if available
-	if appEnv := os.Getenv("APP_ENV"); appEnv != "" {
-		env = appEnv
+	c.Host = e.Host
+	c.Port = e.Port
+	c.PageTimeout = e.PageTimeout
+	c.LinkTimeout = e.LinkTimeout
+	c.UserAgent = e.UserAgent
+	c.MaxLinks = e.MaxLinks
+	c.LinkCheckWorkers = e.LinkCheckWorkers
+	c.HostLimits = e.HostLimits
+	c.LinkCache = e.LinkCache
+	c.MaxRedirects = e.MaxRedirects
+	c.DeniedNetworks = e.DeniedNetworks
+	c.HostPolicy = e.HostPolicy
+	c.Server = e.Server
+	c.History = e.History
+	c.Monitoring = e.Monitoring
+
+	for key, line := range keyLines(data, env) {
+		c.setOrigin(key, fmt.Sprintf("%s:%d", filename, line))
 	}
-	if envs[env].Port != "" {
-		return &Config{
-			ServerAddress: ":" + envs[env].Port,
+	return nil
+}
+
+func environmentNames(envs map[string]Environment) []string {
+	names := make([]string, 0, len(envs))
+	for name := range envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadEnv applies the settings given as environment variables
+func (c *Config) loadEnv() error {
+	var errs []error
+	lookup := func(name, field string) (string, bool) {
+		value := os.Getenv(name)
+		if value == "" {
+			return "", false
 		}
+		c.setOrigin(field, "environment variable "+name)
+		return value, true
 	}
-	return nil
+	text := func(name, field string, dst *string) {
+		if value, ok := lookup(name, field); ok {
+			*dst = value
+		}
+	}
+	list := func(name, field string, dst *[]string) {
+		if value, ok := lookup(name, field); ok {
+			*dst = strings.Split(value, ",")
+		}
+	}
+	number := func(name, field string, dst *int) {
+		if value, ok := lookup(name, field); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("environment variable %s: invalid number %q", name, value))
+				return
+			}
+			*dst = n
+		}
+	}
+	duration := func(name, field string, dst *time.Duration) {
+		if value, ok := lookup(name, field); ok {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("environment variable %s: invalid duration %q", name, value))
+				return
+			}
+			*dst = d
+		}
+	}
+	flag := func(name, field string, dst *bool) {
+		if value, ok := lookup(name, field); ok {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("environment variable %s: invalid boolean %q", name, value))
+				return
+			}
+			*dst = b
+		}
+	}
+
+	text("HOST", "Host", &c.Host)
+	text("PORT", "Port", &c.Port)
+	duration("PAGE_TIMEOUT", "PageTimeout", &c.PageTimeout)
+	duration("LINK_TIMEOUT", "LinkTimeout", &c.LinkTimeout)
+	text("USER_AGENT", "UserAgent", &c.UserAgent)
+	number("MAX_LINKS", "MaxLinks", &c.MaxLinks)
+	number("LINK_CHECK_WORKERS", "LinkCheckWorkers", &c.LinkCheckWorkers)
+	duration("LINK_CACHE_TTL", "LinkCache", &c.LinkCache.TTL)
+	duration("LINK_CACHE_NEGATIVE_TTL", "LinkCache", &c.LinkCache.NegativeTTL)
+	number("LINK_CACHE_SIZE", "LinkCache", &c.LinkCache.MaxEntries)
+	number("MAX_REDIRECTS", "MaxRedirects", &c.MaxRedirects)
+	list("DENIED_NETWORKS", "DeniedNetworks", &c.DeniedNetworks)
+	list("ALLOWED_HOSTS", "HostPolicy", &c.HostPolicy.Allow)
+	list("DENIED_HOSTS", "HostPolicy", &c.HostPolicy.Deny)
+	flag("HOST_POLICY_LINKS", "HostPolicy", &c.HostPolicy.ApplyToLinks)
+	duration("READ_HEADER_TIMEOUT", "Server", &c.Server.ReadHeaderTimeout)
+	duration("READ_TIMEOUT", "Server", &c.Server.ReadTimeout)
+	duration("WRITE_TIMEOUT", "Server", &c.Server.WriteTimeout)
+	duration("IDLE_TIMEOUT", "Server", &c.Server.IdleTimeout)
+	number("MAX_HEADER_BYTES", "Server", &c.Server.MaxHeaderBytes)
+	duration("SHUTDOWN_TIMEOUT", "Server", &c.Server.ShutdownTimeout)
+	text("TLS_CERT_FILE", "Server", &c.Server.TLSCertFile)
+	text("TLS_KEY_FILE", "Server", &c.Server.TLSKeyFile)
+	text("HISTORY_DIR", "History", &c.History.Dir)
+	duration("HISTORY_MAX_AGE", "History", &c.History.MaxAge)
+	number("HISTORY_MAX_ENTRIES", "History", &c.History.MaxEntries)
+	var webhooks []string
+	list("ALERT_WEBHOOKS", "Monitoring", &webhooks)
+	if webhooks != nil {
+		c.Monitoring.Webhooks = nil
+		for _, u := range webhooks {
+			c.Monitoring.Webhooks = append(c.Monitoring.Webhooks, Webhook{URL: strings.TrimSpace(u)})
+		}
+	}
+
+	return errors.Join(errs...)
 }
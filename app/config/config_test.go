@@ -2,11 +2,32 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
+func mustLoad(t *testing.T, file string) *Config {
+	t.Helper()
+	cfg, err := LoadConfig(file)
+	if err != nil {
+		t.Fatalf("unexpected error loading %q: %v", file, err)
+	}
+	return cfg
+}
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
 func TestLoadConfig_Default(t *testing.T) {
-	cfg := LoadConfig("")
+	cfg := mustLoad(t, "")
 
 	if cfg == nil {
 		t.Fatal("expected config to be loaded, got nil")
@@ -25,7 +46,7 @@ func TestLoadConfig_EnvironmentVariables(t *testing.T) {
 		os.Unsetenv("PORT")
 	}()
 
-	cfg := LoadConfig("")
+	cfg := mustLoad(t, "")
 
 	if cfg.ServerAddress != ":9090" {
 		t.Errorf("expected ServerAddress to be :9090, got %s", cfg.ServerAddress)
@@ -33,16 +54,80 @@ func TestLoadConfig_EnvironmentVariables(t *testing.T) {
 }
 
 func TestLoadConfig_InvalidYAML(t *testing.T) {
-	// Test with non-existent config file
-	cfg := LoadConfig("/non/existent/file.yaml")
+	// A missing config file is an error rather than a silent fallback to the defaults
+	cfg, err := LoadConfig("/non/existent/file.yaml")
 
-	if cfg == nil {
-		t.Fatal("expected config to be loaded with defaults, got nil")
+	if err == nil || cfg != nil {
+		t.Fatalf("expected an error for a missing file, got %+v", cfg)
 	}
+	if !strings.Contains(err.Error(), "/non/existent/file.yaml") {
+		t.Errorf("expected the file name in the error, got %v", err)
+	}
+}
 
-	// Should fall back to defaults
-	if cfg.ServerAddress != ":8080" {
-		t.Errorf("expected ServerAddress to be :8080, got %s", cfg.ServerAddress)
+func TestLoadConfig_SyntaxError(t *testing.T) {
+	file := writeConfig(t, "Local:\n  Port: \"8080\"\n  MaxLinks: [\n")
+
+	_, err := LoadConfig(file)
+	if err == nil || !strings.Contains(err.Error(), file) || !strings.Contains(err.Error(), "line") {
+		t.Errorf("expected an error naming the file and line, got %v", err)
+	}
+}
+
+func TestLoadConfig_UnknownKey(t *testing.T) {
+	file := writeConfig(t, "Local:\n  Port: \"8080\"\n  MaxLink: 10\n")
+
+	_, err := LoadConfig(file)
+	if err == nil || !strings.Contains(err.Error(), "line 3") || !strings.Contains(err.Error(), "MaxLink") {
+		t.Errorf("expected the unknown key and its line in the error, got %v", err)
+	}
+}
+
+func TestLoadConfig_UnknownEnvironment(t *testing.T) {
+	file := writeConfig(t, "Local:\n  Port: \"8080\"\nProduction:\n  Port: \"80\"\n")
+	os.Setenv("APP_ENV", "Prod")
+	defer os.Unsetenv("APP_ENV")
+
+	_, err := LoadConfig(file)
+	if err == nil || !strings.Contains(err.Error(), `unknown environment "Prod" (defined: Local, Production)`) {
+		t.Errorf("expected an unknown environment error, got %v", err)
+	}
+}
+
+func TestLoadConfig_Host(t *testing.T) {
+	file := writeConfig(t, "Production:\n  Host: \"0.0.0.0\"\n  Port: \"9000\"\n")
+	os.Setenv("APP_ENV", "Production")
+	defer os.Unsetenv("APP_ENV")
+
+	cfg := mustLoad(t, file)
+	if cfg.ServerAddress != "0.0.0.0:9000" {
+		t.Errorf("expected the configured host to be used, got %s", cfg.ServerAddress)
+	}
+}
+
+func TestLoadConfig_InvalidSettings(t *testing.T) {
+	file := writeConfig(t, `Local:
+  Port: "8080"
+  # limits
+  MaxLinks: -5
+  HostLimits:
+    - Pattern: "ex*ample.com"
+`)
+	os.Setenv("LINK_TIMEOUT", "soon")
+	_, err := LoadConfig(file)
+	os.Unsetenv("LINK_TIMEOUT")
+	if err == nil || !strings.Contains(err.Error(), "LINK_TIMEOUT") {
+		t.Errorf("expected the invalid environment variable to be reported, got %v", err)
+	}
+
+	_, err = LoadConfig(file)
+	if err == nil {
+		t.Fatal("expected invalid settings to be reported")
+	}
+	for _, want := range []string{file + ":4: MaxLinks: must be positive", file + ":5: HostLimits[0].Pattern"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in the error, got %v", want, err)
+		}
 	}
 }
 
@@ -55,3 +140,255 @@ func TestEnvironment_Struct(t *testing.T) {
 		t.Errorf("expected Port to be 8080, got %s", env.Port)
 	}
 }
+
+func TestLoadConfig_LinkCheckWorkers(t *testing.T) {
+	cfg := mustLoad(t, "")
+	if cfg.LinkCheckWorkers != DefaultLinkCheckWorkers {
+		t.Errorf("expected LinkCheckWorkers to default to %d, got %d", DefaultLinkCheckWorkers, cfg.LinkCheckWorkers)
+	}
+
+	os.Setenv("LINK_CHECK_WORKERS", "7")
+	defer os.Unsetenv("LINK_CHECK_WORKERS")
+
+	cfg = mustLoad(t, "")
+	if cfg.LinkCheckWorkers != 7 {
+		t.Errorf("expected LinkCheckWorkers to be 7, got %d", cfg.LinkCheckWorkers)
+	}
+}
+
+func TestLoadConfig_HostLimits(t *testing.T) {
+	file := writeConfig(t, `Local:
+  Port: "8080"
+  HostLimits:
+    - Pattern: "*.example.com"
+      MaxConcurrent: 2
+      Interval: 250ms
+`)
+
+	cfg := mustLoad(t, file)
+	if len(cfg.HostLimits) != 1 {
+		t.Fatalf("expected 1 host limit, got %d", len(cfg.HostLimits))
+	}
+	limit := cfg.HostLimits[0]
+	if limit.Pattern != "*.example.com" || limit.MaxConcurrent != 2 || limit.Interval != 250*time.Millisecond {
+		t.Errorf("unexpected host limit %+v", limit)
+	}
+}
+
+func TestLoadConfig_LinkCache(t *testing.T) {
+	os.Setenv("LINK_CACHE_TTL", "5m")
+	os.Setenv("LINK_CACHE_NEGATIVE_TTL", "30s")
+	os.Setenv("LINK_CACHE_SIZE", "100")
+	defer func() {
+		os.Unsetenv("LINK_CACHE_TTL")
+		os.Unsetenv("LINK_CACHE_NEGATIVE_TTL")
+		os.Unsetenv("LINK_CACHE_SIZE")
+	}()
+
+	cfg := mustLoad(t, "")
+	if cfg.LinkCache.TTL != 5*time.Minute || cfg.LinkCache.NegativeTTL != 30*time.Second || cfg.LinkCache.MaxEntries != 100 {
+		t.Errorf("unexpected link cache settings %+v", cfg.LinkCache)
+	}
+}
+
+func TestLoadConfig_DeniedNetworks(t *testing.T) {
+	os.Setenv("DENIED_NETWORKS", "203.0.114.0/24,8.8.4.4")
+	defer os.Unsetenv("DENIED_NETWORKS")
+
+	cfg := mustLoad(t, "")
+	if len(cfg.DeniedNetworks) != 2 || cfg.DeniedNetworks[0] != "203.0.114.0/24" || cfg.DeniedNetworks[1] != "8.8.4.4" {
+		t.Errorf("unexpected denied networks %v", cfg.DeniedNetworks)
+	}
+}
+
+func TestLoadConfig_HostPolicy(t *testing.T) {
+	os.Setenv("ALLOWED_HOSTS", "example.com,*.example.org")
+	os.Setenv("HOST_POLICY_LINKS", "true")
+	defer os.Unsetenv("ALLOWED_HOSTS")
+	defer os.Unsetenv("HOST_POLICY_LINKS")
+
+	cfg := mustLoad(t, "")
+	if len(cfg.HostPolicy.Allow) != 2 || cfg.HostPolicy.Allow[1] != "*.example.org" {
+		t.Errorf("unexpected allowed hosts %v", cfg.HostPolicy.Allow)
+	}
+	if !cfg.HostPolicy.ApplyToLinks {
+		t.Error("expected the policy to apply to links")
+	}
+}
+
+func TestLoadConfig_AnalysisDefaults(t *testing.T) {
+	cfg := mustLoad(t, "")
+
+	if cfg.PageTimeout != DefaultPageTimeout || cfg.LinkTimeout != DefaultLinkTimeout {
+		t.Errorf("unexpected timeouts %v and %v", cfg.PageTimeout, cfg.LinkTimeout)
+	}
+	if cfg.UserAgent != DefaultUserAgent || cfg.MaxLinks != DefaultMaxLinks || cfg.MaxRedirects != DefaultMaxRedirects {
+		t.Errorf("unexpected defaults %+v", cfg)
+	}
+}
+
+func TestLoadConfig_AnalysisSettings(t *testing.T) {
+	file := writeConfig(t, `Local:
+  Port: "8080"
+  PageTimeout: 20s
+  UserAgent: "Crawler/2.0"
+  MaxLinks: 0
+`)
+	os.Setenv("MAX_LINKS", "50")
+	defer os.Unsetenv("MAX_LINKS")
+
+	cfg := mustLoad(t, file)
+	if cfg.PageTimeout != 20*time.Second || cfg.UserAgent != "Crawler/2.0" || cfg.MaxLinks != 50 {
+		t.Errorf("unexpected settings %+v", cfg)
+	}
+	if cfg.LinkTimeout != DefaultLinkTimeout {
+		t.Errorf("expected an unset link timeout to be defaulted, got %v", cfg.LinkTimeout)
+	}
+}
+
+func TestLoadConfig_PageTimeoutShorterThanLinkTimeout(t *testing.T) {
+	os.Setenv("PAGE_TIMEOUT", "5s")
+	defer os.Unsetenv("PAGE_TIMEOUT")
+
+	// Each bounds its own requests, so a short page timeout leaves the link timeout alone
+	cfg := mustLoad(t, "")
+	if cfg.PageTimeout != 5*time.Second || cfg.LinkTimeout != DefaultLinkTimeout {
+		t.Errorf("unexpected timeouts %v and %v", cfg.PageTimeout, cfg.LinkTimeout)
+	}
+}
+
+func TestLoadConfig_Server(t *testing.T) {
+	file := writeConfig(t, `Local:
+  Server:
+    WriteTimeout: 90s
+    ShutdownTimeout: 5s
+`)
+	os.Setenv("MAX_HEADER_BYTES", "4096")
+	defer os.Unsetenv("MAX_HEADER_BYTES")
+
+	cfg := mustLoad(t, file)
+	if cfg.Server.WriteTimeout != 90*time.Second || cfg.Server.ShutdownTimeout != 5*time.Second || cfg.Server.MaxHeaderBytes != 4096 {
+		t.Errorf("unexpected server settings %+v", cfg.Server)
+	}
+	if cfg.Server.ReadHeaderTimeout != DefaultReadHeaderTimeout || cfg.Server.IdleTimeout != DefaultIdleTimeout {
+		t.Errorf("expected unset server timeouts to be defaulted, got %+v", cfg.Server)
+	}
+}
+
+func TestLoadConfig_InvalidServer(t *testing.T) {
+	file := writeConfig(t, `Local:
+  PageTimeout: 30s
+  Server:
+    WriteTimeout: -10s
+    IdleTimeout: -1s
+    TLSCertFile: cert.pem
+`)
+
+	_, err := LoadConfig(file)
+	if err == nil {
+		t.Fatal("expected the server settings to be rejected")
+	}
+	for _, want := range []string{
+		"config.yaml:3: Server.WriteTimeout: must not be negative, got -10s",
+		"Server.IdleTimeout: must not be negative",
+		"Server.TLSKeyFile: must be set along with TLSCertFile",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestLoadConfig_MissingTLSFiles(t *testing.T) {
+	os.Setenv("TLS_CERT_FILE", "/non/existent/cert.pem")
+	os.Setenv("TLS_KEY_FILE", "/non/existent/key.pem")
+	defer os.Unsetenv("TLS_CERT_FILE")
+	defer os.Unsetenv("TLS_KEY_FILE")
+
+	_, err := LoadConfig("")
+	if err == nil || !strings.Contains(err.Error(), "Server.TLSCertFile: stat /non/existent/cert.pem") {
+		t.Errorf("expected the missing certificate to be reported, got %v", err)
+	}
+}
+
+func TestLoadConfig_History(t *testing.T) {
+	file := writeConfig(t, `Local:
+  History:
+    Dir: /var/lib/page-insight-tool
+    MaxAge: 168h
+`)
+	os.Setenv("HISTORY_MAX_ENTRIES", "-1")
+	defer os.Unsetenv("HISTORY_MAX_ENTRIES")
+
+	_, err := LoadConfig(file)
+	if err == nil || !strings.Contains(err.Error(), "environment variable HISTORY_MAX_ENTRIES: History.MaxEntries: must not be negative") {
+		t.Errorf("expected the negative retention to be rejected, got %v", err)
+	}
+
+	os.Unsetenv("HISTORY_MAX_ENTRIES")
+	cfg := mustLoad(t, file)
+	if cfg.History.Dir != "/var/lib/page-insight-tool" || cfg.History.MaxAge != 168*time.Hour || cfg.History.MaxEntries != DefaultHistoryMaxEntries {
+		t.Errorf("unexpected history settings %+v", cfg.History)
+	}
+}
+
+func TestLoadConfig_Monitoring(t *testing.T) {
+	file := writeConfig(t, `Local:
+  Monitoring:
+    Monitors:
+      - Name: home
+        URL: https://example.com/
+        Schedule: "*/15 * * * *"
+        Rules: [new_broken_links, non_200]
+      - Name: home
+        URL: example.com
+        Schedule: "@every 1ms"
+        Rules: [slow]
+    Webhooks:
+      - URL: https://hooks.example.com/alerts
+`)
+	_, err := LoadConfig(file)
+	for _, want := range []string{
+		`Monitoring.Monitors[1].Name: "home" is already used`,
+		`Monitoring.Monitors[1].URL: must be an http or https URL`,
+		`Monitoring.Monitors[1].Schedule: invalid schedule "@every 1ms"`,
+		`Monitoring.Monitors[1].Rules[0]: unknown rule "slow"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q, got %v", want, err)
+		}
+	}
+
+	file = writeConfig(t, `Local:
+  Monitoring:
+    Monitors:
+      - Name: home
+        URL: https://example.com/
+        Schedule: "@hourly"
+    Webhooks:
+      - URL: https://hooks.example.com/alerts
+`)
+	cfg := mustLoad(t, file)
+	if len(cfg.Monitoring.Monitors) != 1 || cfg.Monitoring.Webhooks[0].URL != "https://hooks.example.com/alerts" {
+		t.Errorf("unexpected monitoring settings %+v", cfg.Monitoring)
+	}
+
+	os.Setenv("ALERT_WEBHOOKS", "http://localhost:9000/a, http://localhost:9000/b")
+	defer os.Unsetenv("ALERT_WEBHOOKS")
+	cfg = mustLoad(t, file)
+	if len(cfg.Monitoring.Webhooks) != 2 || cfg.Monitoring.Webhooks[1].URL != "http://localhost:9000/b" {
+		t.Errorf("expected the webhooks of ALERT_WEBHOOKS, got %+v", cfg.Monitoring.Webhooks)
+	}
+}
+
+func TestLoadConfig_ShippedFile(t *testing.T) {
+	envs, err := Environments("page-insight-tool.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, env := range envs {
+		if _, err := LoadEnvironment("page-insight-tool.yaml", env); err != nil {
+			t.Errorf("%s: %v", env, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"flag"
+	"time"
+)
+
+// Flags holds the command line overrides of the configuration. They win over
+// the YAML file and the environment variables.
+type Flags struct {
+	fs           *flag.FlagSet
+	pageTimeout  time.Duration
+	linkTimeout  time.Duration
+	userAgent    string
+	maxLinks     int
+	workers      int
+	maxRedirects int
+}
+
+// RegisterFlags defines the override flags on fs
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs}
+	fs.DurationVar(&f.pageTimeout, "page-timeout", DefaultPageTimeout, "timeout for fetching the analyzed page")
+	fs.DurationVar(&f.linkTimeout, "link-timeout", DefaultLinkTimeout, "timeout for checking one link")
+	fs.StringVar(&f.userAgent, "user-agent", DefaultUserAgent, "User-Agent header sent with every request")
+	fs.IntVar(&f.maxLinks, "max-links", DefaultMaxLinks, "maximum number of links checked per page")
+	fs.IntVar(&f.workers, "workers", DefaultLinkCheckWorkers, "number of link checks run at the same time")
+	fs.IntVar(&f.maxRedirects, "max-redirects", DefaultMaxRedirects, "redirect chains longer than this are flagged")
+	return f
+}
+
+// Apply overrides cfg with the flags given on the command line, once fs is parsed,
+// and validates the result
+func (f *Flags) Apply(cfg *Config) error {
+	f.fs.Visit(func(fl *flag.Flag) {
+		field := ""
+		switch fl.Name {
+		case "page-timeout":
+			cfg.PageTimeout, field = f.pageTimeout, "PageTimeout"
+		case "link-timeout":
+			cfg.LinkTimeout, field = f.linkTimeout, "LinkTimeout"
+		case "user-agent":
+			cfg.UserAgent, field = f.userAgent, "UserAgent"
+		case "max-links":
+			cfg.MaxLinks, field = f.maxLinks, "MaxLinks"
+		case "workers":
+			cfg.LinkCheckWorkers, field = f.workers, "LinkCheckWorkers"
+		case "max-redirects":
+			cfg.MaxRedirects, field = f.maxRedirects, "MaxRedirects"
+		default:
+			return
+		}
+		cfg.setOrigin(field, "flag -"+fl.Name)
+	})
+	return cfg.finish()
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlags_Apply(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flags := RegisterFlags(fs)
+	if err := fs.Parse([]string{"-link-timeout", "3s", "-user-agent", "Bot/1", "-workers", "8"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{MaxLinks: 42, MaxRedirects: 7}
+	if err := flags.Apply(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.LinkTimeout != 3*time.Second || cfg.UserAgent != "Bot/1" || cfg.LinkCheckWorkers != 8 {
+		t.Errorf("expected the given flags to override the config, got %+v", cfg)
+	}
+	if cfg.MaxLinks != 42 || cfg.MaxRedirects != 7 {
+		t.Errorf("expected flags left unset to keep the config values, got %+v", cfg)
+	}
+	if cfg.PageTimeout != DefaultPageTimeout {
+		t.Errorf("expected unset settings to be defaulted, got %v", cfg.PageTimeout)
+	}
+}
+
+func TestFlags_ApplyInvalid(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flags := RegisterFlags(fs)
+	if err := fs.Parse([]string{"-max-links", "-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := flags.Apply(&Config{})
+	if err == nil || !strings.Contains(err.Error(), "flag -max-links: MaxLinks") {
+		t.Errorf("expected the invalid flag to be reported, got %v", err)
+	}
+}
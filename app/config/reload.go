@@ -0,0 +1,187 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultReloadInterval is how often the configuration file is checked for changes
+const DefaultReloadInterval = 2 * time.Second
+
+// restartRequired lists the settings only read when the server starts
+var restartRequired = map[string]bool{"Host": true, "Port": true, "Server": true, "History": true}
+
+// Reloader holds the running configuration and replaces it when its file changes
+// or when asked to. A configuration that fails to load or validate is logged and
+// the running one is kept.
+type Reloader struct {
+	file   string
+	load   func() (*Config, error)
+	apply  func(*Config) error
+	logger *slog.Logger
+
+	mu      sync.Mutex // serializes reloads
+	current atomic.Pointer[Config]
+	stamp   fileStamp // of the file when last loaded
+}
+
+// fileStamp identifies a version of the configuration file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewReloader returns a reloader running cfg, loaded from file. load reads and
+// validates the configuration again; apply hands a new one to the running server.
+func NewReloader(file string, cfg *Config, load func() (*Config, error), apply func(*Config) error) *Reloader {
+	r := &Reloader{file: file, load: load, apply: apply, logger: slog.Default()}
+	r.current.Store(cfg)
+	r.stamp, _ = stat(file)
+	return r
+}
+
+// Current returns the running configuration
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// Reload loads the configuration again and applies it when valid, logging what changed
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stamp, err := stat(r.file); err == nil {
+		r.stamp = stamp
+	}
+	next, err := r.load()
+	if err == nil {
+		err = r.apply(next)
+	}
+	if err != nil {
+		r.logger.Error("config reload failed, keeping the running configuration", "file", r.file, "error", err)
+		return err
+	}
+
+	changes := Diff(r.Current(), next)
+	r.current.Store(next)
+	if len(changes) == 0 {
+		r.logger.Info("config reloaded, nothing changed", "file", r.file)
+		return nil
+	}
+	for _, change := range changes {
+		r.logger.Info("config changed", "change", change)
+	}
+	return nil
+}
+
+// Watch reloads the configuration when its file changes, checked every interval,
+// and whenever a signal is received on trigger. It returns once ctx is done.
+// A zero interval or an empty file name disables watching the file.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, trigger <-chan os.Signal) {
+	var tick <-chan time.Time
+	if interval > 0 && r.file != "" {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-trigger:
+			r.logger.Info("config reload requested", "signal", sig.String(), "file", r.file)
+			_ = r.Reload()
+		case <-tick:
+			if !r.changed() {
+				continue
+			}
+			r.logger.Info("config file changed, reloading", "file", r.file)
+			_ = r.Reload()
+		}
+	}
+}
+
+// changed reports whether the file differs from the version last loaded
+func (r *Reloader) changed() bool {
+	stamp, err := stat(r.file)
+	if err != nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !stamp.modTime.Equal(r.stamp.modTime) || stamp.size != r.stamp.size
+}
+
+func stat(file string) (fileStamp, error) {
+	if file == "" {
+		return fileStamp{}, os.ErrNotExist
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// Diff lists the settings that differ between two configurations as
+// "Setting: old -> new", noting those only applied on restart
+func Diff(old, next *Config) []string {
+	var changes []string
+	before, after := reflect.ValueOf(*old), reflect.ValueOf(*next)
+	fields := before.Type()
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Field(i)
+		// ServerAddress follows Host and Port
+		if !field.IsExported() || field.Name == "ServerAddress" {
+			continue
+		}
+		// Compared as printed, so an empty list equals a missing one
+		a := fmt.Sprintf("%+v", before.Field(i).Interface())
+		b := fmt.Sprintf("%+v", after.Field(i).Interface())
+		if a == b {
+			continue
+		}
+		change := fmt.Sprintf("%s: %+v -> %+v", field.Name, redacted(before.Field(i).Interface()), redacted(after.Field(i).Interface()))
+		if restartRequired[field.Name] {
+			change += " (applies after a restart)"
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// redacted returns v as logged. Webhook URLs carry their secret in the path or the
+// query, so only their scheme and host are shown.
+func redacted(v interface{}) interface{} {
+	m, ok := v.(Monitoring)
+	if !ok {
+		return v
+	}
+	webhooks := make([]Webhook, len(m.Webhooks))
+	for i, w := range m.Webhooks {
+		w.URL = redactURL(w.URL)
+		webhooks[i] = w
+	}
+	m.Webhooks = webhooks
+	return m
+}
+
+// redactURL keeps the scheme and host of rawURL
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "<redacted>"
+	}
+	if u.User == nil && (u.Path == "" || u.Path == "/") && u.RawQuery == "" {
+		return rawURL
+	}
+	return u.Scheme + "://" + u.Host + "/<redacted>"
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	old := &Config{Port: "8080", MaxLinks: 500, LinkTimeout: 10 * time.Second}
+	next := &Config{Port: "9090", MaxLinks: 500, LinkTimeout: 5 * time.Second, DeniedNetworks: []string{}}
+
+	changes := Diff(old, next)
+	want := []string{
+		"Port: 8080 -> 9090 (applies after a restart)",
+		"LinkTimeout: 10s -> 5s",
+	}
+	if strings.Join(changes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected %q, got %q", want, changes)
+	}
+}
+
+func TestDiff_RedactsWebhooks(t *testing.T) {
+	old := &Config{Monitoring: Monitoring{Webhooks: []Webhook{{URL: "https://hooks.example.com/services/T000/B000/secret"}}}}
+	next := &Config{Monitoring: Monitoring{Webhooks: []Webhook{{URL: "https://hooks.example.com/services/T000/B000/rotated?token=abc"}}}}
+
+	changes := Diff(old, next)
+	if len(changes) != 1 || !strings.Contains(changes[0], "https://hooks.example.com/<redacted>") {
+		t.Fatalf("expected the webhook change with its URL redacted, got %q", changes)
+	}
+	if strings.Contains(changes[0], "secret") || strings.Contains(changes[0], "rotated") || strings.Contains(changes[0], "abc") {
+		t.Errorf("expected no secret in the change, got %q", changes[0])
+	}
+}
+
+// reloadRecorder loads the configurations queued in it and records what is applied and logged
+type reloadRecorder struct {
+	mu      sync.Mutex
+	next    []*Config
+	errs    []error
+	applied []*Config
+	logs    bytes.Buffer
+}
+
+func (r *reloadRecorder) load() (*Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cfg, err := r.next[0], r.errs[0]
+	r.next, r.errs = r.next[1:], r.errs[1:]
+	return cfg, err
+}
+
+func (r *reloadRecorder) apply(cfg *Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.applied = append(r.applied, cfg)
+	return nil
+}
+
+func TestReloader_Reload(t *testing.T) {
+	running := &Config{Port: "8080", MaxLinks: 500}
+	rec := &reloadRecorder{
+		next: []*Config{nil, {Port: "8080", MaxLinks: 100}},
+		errs: []error{errors.New("config.yaml:3: MaxLinks: must be positive, got -1"), nil},
+	}
+	r := NewReloader("", running, rec.load, rec.apply)
+	r.logger = slog.New(slog.NewTextHandler(&rec.logs, nil))
+
+	if err := r.Reload(); err == nil {
+		t.Fatal("expected the invalid configuration to be reported")
+	}
+	if r.Current() != running || len(rec.applied) != 0 {
+		t.Error("expected the running configuration to be kept")
+	}
+
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Current().MaxLinks != 100 || len(rec.applied) != 1 {
+		t.Errorf("expected the new configuration to be applied, got %+v", r.Current())
+	}
+	logs := rec.logs.String()
+	if !strings.Contains(logs, "keeping the running configuration") || !strings.Contains(logs, "MaxLinks: 500 -> 100") {
+		t.Errorf("expected the failure and the diff to be logged, got %q", logs)
+	}
+}
+
+func TestReloader_Watch(t *testing.T) {
+	file := writeConfig(t, "Local:\n  Port: \"8080\"\n  MaxLinks: 10\n")
+	load := func() (*Config, error) { return LoadEnvironment(file, "Local") }
+	cfg, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied := make(chan *Config, 4)
+	r := NewReloader(file, cfg, load, func(cfg *Config) error {
+		applied <- cfg
+		return nil
+	})
+	r.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	trigger := make(chan os.Signal, 1)
+	go r.Watch(ctx, 5*time.Millisecond, trigger)
+
+	if err := os.WriteFile(file, []byte("Local:\n  Port: \"8080\"\n  MaxLinks: 20\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case cfg := <-applied:
+		if cfg.MaxLinks != 20 {
+			t.Errorf("expected the edited file to be applied, got %+v", cfg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the file change to be picked up")
+	}
+
+	trigger <- syscall.SIGHUP
+	select {
+	case <-applied:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a signal to reload the configuration")
+	}
+}
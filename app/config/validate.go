@@ -0,0 +1,231 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rabie/page-insight-tool/app/cron"
+	"github.com/rabie/page-insight-tool/app/helper"
+	"github.com/rabie/page-insight-tool/app/rule"
+)
+
+// Validate reports every invalid setting, each prefixed with where it was given:
+// the file and line, the environment variable or the command line flag
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(field, format string, args ...interface{}) {
+		msg := field + ": " + fmt.Sprintf(format, args...)
+		// Nested settings are located by their top-level key
+		key := field
+		if i := strings.IndexAny(key, ".["); i >= 0 {
+			key = key[:i]
+		}
+		if origin := c.origins[key]; origin != "" {
+			msg = origin + ": " + msg
+		}
+		errs = append(errs, errors.New(msg))
+	}
+
+	if strings.ContainsFunc(c.Host, unicode.IsSpace) || strings.Contains(c.Host, "/") {
+		invalid("Host", "invalid host %q", c.Host)
+	}
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		invalid("Port", "must be a number between 1 and 65535, got %q", c.Port)
+	}
+	if c.PageTimeout < 0 {
+		invalid("PageTimeout", "must be positive, got %v", c.PageTimeout)
+	}
+	if c.LinkTimeout < 0 {
+		invalid("LinkTimeout", "must be positive, got %v", c.LinkTimeout)
+	}
+	if strings.ContainsFunc(c.UserAgent, unicode.IsControl) {
+		invalid("UserAgent", "must not contain control characters")
+	}
+	if c.MaxLinks < 0 {
+		invalid("MaxLinks", "must be positive, got %d", c.MaxLinks)
+	}
+	if c.LinkCheckWorkers < 0 {
+		invalid("LinkCheckWorkers", "must be positive, got %d", c.LinkCheckWorkers)
+	}
+	if c.MaxRedirects < 0 {
+		invalid("MaxRedirects", "must be positive, got %d", c.MaxRedirects)
+	}
+
+	for i, limit := range c.HostLimits {
+		field := fmt.Sprintf("HostLimits[%d]", i)
+		if !validPattern(limit.Pattern) {
+			invalid(field+".Pattern", "must be a host, a \"*.\" wildcard suffix or \"*\", got %q", limit.Pattern)
+		}
+		if limit.MaxConcurrent < 0 {
+			invalid(field+".MaxConcurrent", "must not be negative, got %d", limit.MaxConcurrent)
+		}
+		if limit.Interval < 0 {
+			invalid(field+".Interval", "must not be negative, got %v", limit.Interval)
+		}
+	}
+
+	if c.LinkCache.TTL < 0 {
+		invalid("LinkCache.TTL", "must not be negative, got %v", c.LinkCache.TTL)
+	}
+	if c.LinkCache.NegativeTTL < 0 {
+		invalid("LinkCache.NegativeTTL", "must not be negative, got %v", c.LinkCache.NegativeTTL)
+	}
+	if c.LinkCache.MaxEntries < 0 {
+		invalid("LinkCache.MaxEntries", "must not be negative, got %d", c.LinkCache.MaxEntries)
+	}
+
+	if _, err := helper.NewIPDenyList(c.DeniedNetworks); err != nil {
+		invalid("DeniedNetworks", "%v", err)
+	}
+	if _, err := helper.NewHostPolicy(c.HostPolicy.Allow, c.HostPolicy.Deny); err != nil {
+		invalid("HostPolicy", "%v", err)
+	}
+
+	c.validateServer(invalid)
+
+	if c.History.MaxAge < 0 {
+		invalid("History.MaxAge", "must not be negative, got %v", c.History.MaxAge)
+	}
+	if c.History.MaxEntries < 0 {
+		invalid("History.MaxEntries", "must not be negative, got %d", c.History.MaxEntries)
+	}
+
+	c.validateMonitoring(invalid)
+	return errors.Join(errs...)
+}
+
+// validateMonitoring checks the Monitoring section
+func (c *Config) validateMonitoring(invalid func(field, format string, args ...interface{})) {
+	names := make(map[string]bool)
+	for i, m := range c.Monitoring.Monitors {
+		field := fmt.Sprintf("Monitoring.Monitors[%d]", i)
+		switch {
+		case m.Name == "":
+			invalid(field+".Name", "must be set")
+		case names[m.Name]:
+			invalid(field+".Name", "%q is already used by another monitor", m.Name)
+		}
+		names[m.Name] = true
+		if !validHTTPURL(m.URL) {
+			invalid(field+".URL", "must be an http or https URL, got %q", m.URL)
+		}
+		if _, err := cron.Parse(m.Schedule); err != nil {
+			invalid(field+".Schedule", "%v", err)
+		}
+		for j, name := range m.Rules {
+			if _, err := rule.Parse(name); err != nil {
+				invalid(fmt.Sprintf("%s.Rules[%d]", field, j), "%v", err)
+			}
+		}
+	}
+	for i, w := range c.Monitoring.Webhooks {
+		field := fmt.Sprintf("Monitoring.Webhooks[%d]", i)
+		if !validHTTPURL(w.URL) {
+			invalid(field+".URL", "must be an http or https URL, got %q", w.URL)
+		}
+		if w.Timeout < 0 {
+			invalid(field+".Timeout", "must not be negative, got %v", w.Timeout)
+		}
+	}
+}
+
+// validHTTPURL reports whether rawURL is an absolute http or https URL
+func validHTTPURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validateServer checks the Server section
+func (c *Config) validateServer(invalid func(field, format string, args ...interface{})) {
+	s := c.Server
+	durations := []struct {
+		field string
+		value time.Duration
+	}{
+		{"ReadHeaderTimeout", s.ReadHeaderTimeout},
+		{"ReadTimeout", s.ReadTimeout},
+		{"WriteTimeout", s.WriteTimeout},
+		{"IdleTimeout", s.IdleTimeout},
+		{"ShutdownTimeout", s.ShutdownTimeout},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			invalid("Server."+d.field, "must not be negative, got %v", d.value)
+		}
+	}
+	if s.MaxHeaderBytes < 0 {
+		invalid("Server.MaxHeaderBytes", "must not be negative, got %d", s.MaxHeaderBytes)
+	}
+
+	switch {
+	case s.TLSCertFile == "" && s.TLSKeyFile == "":
+	case s.TLSCertFile == "":
+		invalid("Server.TLSCertFile", "must be set along with TLSKeyFile")
+	case s.TLSKeyFile == "":
+		invalid("Server.TLSKeyFile", "must be set along with TLSCertFile")
+	default:
+		if _, err := os.Stat(s.TLSCertFile); err != nil {
+			invalid("Server.TLSCertFile", "%v", err)
+		}
+		if _, err := os.Stat(s.TLSKeyFile); err != nil {
+			invalid("Server.TLSKeyFile", "%v", err)
+		}
+	}
+}
+
+// validPattern reports whether pattern is an exact host, a wildcard suffix or "*"
+func validPattern(pattern string) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasPrefix(pattern, "*."):
+		pattern = pattern[2:]
+	}
+	return pattern != "" && !strings.ContainsAny(pattern, "*/ ")
+}
+
+// keyLines returns the line of each key of the env section of a YAML file.
+// yaml.v2 does not report positions once decoded, so the lines are found by
+// scanning the top-level keys of the section.
+func keyLines(data []byte, env string) map[string]int {
+	lines := make(map[string]int)
+	inSection := false
+	indent := -1
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		depth := len(line) - len(strings.TrimLeft(line, " "))
+		if depth == 0 {
+			key, _, _ := strings.Cut(trimmed, ":")
+			inSection = strings.Trim(key, `"'`) == env
+			indent = -1
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if indent < 0 {
+			indent = depth
+		}
+		if depth != indent || strings.HasPrefix(trimmed, "-") {
+			continue
+		}
+		if key, _, ok := strings.Cut(trimmed, ":"); ok {
+			lines[strings.Trim(key, `"'`)] = n
+		}
+	}
+	return lines
+}
@@ -8,8 +8,24 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/checker"
+	"github.com/rabie/page-insight-tool/app/jobs"
 )
 
+// newTestHandler returns a Handler backed by real services with small limits
+func newTestHandler(t *testing.T) *Handler {
+	pool := checker.NewPool(4)
+	a := analyzer.New(pool, nil, analyzer.DefaultOptions())
+	jobManager := jobs.NewManager(jobs.Options{Workers: 1}, a.AnalyzePage)
+	t.Cleanup(func() {
+		jobManager.Close()
+		pool.Close()
+	})
+	return New(a, pool, jobManager)
+}
+
 func TestIndexHandler(t *testing.T) {
 	// Create a temporary template file for testing
 	tempDir := t.TempDir()
@@ -47,7 +63,7 @@ func TestIndexHandler(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(IndexHandler)
+	handler := http.HandlerFunc(newTestHandler(t).IndexHandler)
 
 	handler.ServeHTTP(rr, req)
 
@@ -70,7 +86,7 @@ func TestAnalyzeHandler_EmptyURL(t *testing.T) {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(AnalyzeHandler)
+	handler := http.HandlerFunc(newTestHandler(t).AnalyzeHandler)
 
 	handler.ServeHTTP(rr, req)
 
@@ -90,7 +106,7 @@ func TestAnalyzeHandler_ValidURL(t *testing.T) {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(AnalyzeHandler)
+	handler := http.HandlerFunc(newTestHandler(t).AnalyzeHandler)
 
 	handler.ServeHTTP(rr, req)
 
@@ -104,81 +120,3 @@ func TestAnalyzeHandler_ValidURL(t *testing.T) {
 		t.Errorf("handler returned unexpected status code: got %v", rr.Code)
 	}
 }
-
-func TestAnalyzePage_InvalidURL(t *testing.T) {
-	analysis := analyzePage("invalid-url")
-
-	if analysis.Error.Message == "" {
-		t.Error("expected error for invalid URL, got none")
-	}
-}
-
-func TestAnalyzePage_PrivateIP(t *testing.T) {
-	analysis := analyzePage("http://192.168.1.1")
-
-	if analysis.Error.Message == "" {
-		t.Error("expected error for private IP, got none")
-	}
-
-	if !strings.Contains(analysis.Error.Message, "access to private network denied") {
-		t.Errorf("expected private network error, got: %s", analysis.Error.Message)
-	}
-}
-
-func TestValidateURL_ValidURL(t *testing.T) {
-	u, _ := url.Parse("https://example.com")
-	err := validateURL(u)
-
-	if err != nil {
-		t.Errorf("expected no error for valid URL, got: %v", err)
-	}
-}
-
-func TestValidateURL_InvalidScheme(t *testing.T) {
-	u, _ := url.Parse("ftp://example.com")
-	err := validateURL(u)
-
-	if err == nil {
-		t.Error("expected error for invalid scheme, got none")
-	}
-
-	if !strings.Contains(err.Error(), "unsupported URL scheme") {
-		t.Errorf("expected scheme error, got: %s", err.Error())
-	}
-}
-
-func TestIsLinkAccessible(t *testing.T) {
-	// Test accessible link (using a known working URL)
-	accessibleURL, _ := url.Parse("https://httpbin.org/status/200")
-	if !isLinkAccessible(accessibleURL) {
-		t.Error("expected https://httpbin.org/status/200 to be accessible")
-	}
-
-	// Test inaccessible link (using a known 404 URL)
-	inaccessibleURL, _ := url.Parse("https://httpbin.org/status/404")
-	if isLinkAccessible(inaccessibleURL) {
-		t.Error("expected https://httpbin.org/status/404 to be inaccessible")
-	}
-
-	// Test non-HTTP schemes
-	mailtoURL, _ := url.Parse("mailto:test@example.com")
-	if isLinkAccessible(mailtoURL) {
-		t.Error("expected mailto: scheme to be inaccessible")
-	}
-
-	telURL, _ := url.Parse("tel:+1234567890")
-	if isLinkAccessible(telURL) {
-		t.Error("expected tel: scheme to be inaccessible")
-	}
-
-	javascriptURL, _ := url.Parse("javascript:alert('test')")
-	if isLinkAccessible(javascriptURL) {
-		t.Error("expected javascript: scheme to be inaccessible")
-	}
-
-	// Test fragment-only links
-	fragmentURL, _ := url.Parse("#section")
-	if isLinkAccessible(fragmentURL) {
-		t.Error("expected fragment-only link to be inaccessible")
-	}
-}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/helper"
+)
+
+const (
+	mimeHTML = "text/html"
+	mimeJSON = "application/json"
+)
+
+// analyzeRequest is the JSON body accepted by the API
+type analyzeRequest struct {
+	URL string `json:"url"`
+}
+
+// APIAnalyzeHandler serves GET/POST /api/v1/analyze and always answers with JSON
+func (h *Handler) APIAnalyzeHandler(w http.ResponseWriter, r *http.Request) {
+	urlStr, err := requestedURL(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if urlStr == "" {
+		writeAPIError(w, http.StatusBadRequest, "URL is required")
+		return
+	}
+
+	result := h.save(r.Context(), h.analyzer.AnalyzePage(r.Context(), urlStr))
+	writeJSON(w, httpStatus(result.Error), result)
+}
+
+// requestedURL reads the target URL from a JSON body, a form or the query string
+func requestedURL(r *http.Request) (string, error) {
+	if r.Method == http.MethodPost && isJSONContent(r) {
+		var req analyzeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(req.URL), nil
+	}
+	return strings.TrimSpace(r.FormValue("url")), nil
+}
+
+func isJSONContent(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == mimeJSON
+}
+
+// httpStatus maps an analysis error to the status code returned to API clients
+func httpStatus(e analyzer.LinkError) int {
+	if e.Message == "" {
+		return http.StatusOK
+	}
+	switch e.Kind {
+	case helper.ErrorKindInvalidURL:
+		return http.StatusBadRequest
+	case helper.ErrorKindBlocked, helper.ErrorKindPolicy:
+		return http.StatusForbidden
+	case helper.ErrorKindTimeout:
+		return http.StatusGatewayTimeout
+	case helper.ErrorKindCanceled:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", mimeJSON+"; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, struct {
+		Error analyzer.LinkError `json:"error"`
+	}{Error: analyzer.LinkError{Message: message}})
+}
+
+// negotiate picks the offered media type that best matches the Accept header.
+// Ties go to the earlier offer, so the first one is also the default.
+func negotiate(r *http.Request, offers ...string) string {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return offers[0]
+	}
+
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+
+	best, bestQ := offers[0], 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, rng := range ranges {
+			if s := matchMediaType(rng.mediaType, offer); s > specificity {
+				q, specificity = rng.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// matchMediaType reports how specifically a media range matches a type:
+// 2 for an exact match, 1 for type/*, 0 for */* and -1 for no match
+func matchMediaType(mediaRange, mediaType string) int {
+	if mediaRange == mediaType {
+		return 2
+	}
+	if mediaRange == "*/*" {
+		return 0
+	}
+	if strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")) {
+		return 1
+	}
+	return -1
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/helper"
+)
+
+func TestAPIAnalyzeHandler_EmptyURL(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/v1/analyze", nil)
+	rr := httptest.NewRecorder()
+
+	http.HandlerFunc(newTestHandler(t).APIAnalyzeHandler).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, mimeJSON) {
+		t.Errorf("expected JSON content type, got %s", ct)
+	}
+}
+
+func TestAPIAnalyzeHandler_PrivateIP(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/v1/analyze", strings.NewReader(`{"url": "http://192.168.1.1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	http.HandlerFunc(newTestHandler(t).APIAnalyzeHandler).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+
+	var body analyzer.PageAnalysis
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.URL != "http://192.168.1.1" {
+		t.Errorf("expected url to be echoed, got %q", body.URL)
+	}
+	if body.Error.Kind != helper.ErrorKindBlocked {
+		t.Errorf("expected error kind %q, got %q", helper.ErrorKindBlocked, body.Error.Kind)
+	}
+}
+
+func TestAPIAnalyzeHandler_InvalidScheme(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/v1/analyze?url="+url.QueryEscape("ftp://example.com"), nil)
+	rr := httptest.NewRecorder()
+
+	http.HandlerFunc(newTestHandler(t).APIAnalyzeHandler).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAPIAnalyzeHandler_MalformedBody(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/v1/analyze", strings.NewReader(`{"url":`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	http.HandlerFunc(newTestHandler(t).APIAnalyzeHandler).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAnalyzeHandler_AcceptJSON(t *testing.T) {
+	data := url.Values{}
+	data.Set("url", "http://10.0.0.1")
+
+	req := httptest.NewRequest("POST", "/analyze", strings.NewReader(data.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	rr := httptest.NewRecorder()
+
+	http.HandlerFunc(newTestHandler(t).AnalyzeHandler).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, mimeJSON) {
+		t.Errorf("expected JSON content type, got %s", ct)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", mimeHTML},
+		{"*/*", mimeHTML},
+		{"application/json", mimeJSON},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", mimeHTML},
+		{"*/*;q=0.5, application/json", mimeJSON},
+		{"text/html;q=0.2, application/*;q=0.5", mimeJSON},
+		{"application/json;q=0, */*", mimeHTML},
+		{"image/png", mimeHTML},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		if got := negotiate(req, mimeHTML, mimeJSON); got != tt.want {
+			t.Errorf("negotiate(%q) = %s, want %s", tt.accept, got, tt.want)
+		}
+	}
+}
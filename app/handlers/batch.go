@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+)
+
+const (
+	maxBatchURLs        = 1000
+	maxBatchConcurrency = 10
+	maxBatchBodyBytes   = 1 << 20
+)
+
+// BatchHandler serves POST /api/v1/batch. The URL list is read from a JSON array,
+// CSV or newline separated text body, or from a "file" upload or "urls" field of a form.
+func (h *Handler) BatchHandler(w http.ResponseWriter, r *http.Request) {
+	// The report is written once every page is analyzed, which may outlive the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)
+
+	urls, err := batchURLs(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(urls) == 0 {
+		writeAPIError(w, http.StatusBadRequest, "At least one URL is required")
+		return
+	}
+	if len(urls) > maxBatchURLs {
+		writeAPIError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("A batch accepts at most %d URLs", maxBatchURLs))
+		return
+	}
+
+	concurrency := analyzer.DefaultBatchConcurrency
+	if v := r.URL.Query().Get("concurrency"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxBatchConcurrency {
+			writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("concurrency must be between 1 and %d", maxBatchConcurrency))
+			return
+		}
+		concurrency = n
+	}
+
+	writeJSON(w, http.StatusOK, h.analyzer.AnalyzeBatch(r.Context(), urls, concurrency))
+}
+
+// batchURLs extracts the URL list according to the request's content type
+func batchURLs(r *http.Request) ([]string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(maxBatchBodyBytes); err != nil {
+			return nil, errors.New("invalid multipart form")
+		}
+		file, header, err := r.FormFile("file")
+		if err == nil {
+			defer file.Close()
+			return analyzer.ParseURLList(file, formatFromFilename(header.Filename, header.Header.Get("Content-Type")))
+		}
+		return analyzer.ParseURLList(strings.NewReader(r.FormValue("urls")), analyzer.FormatText)
+	case "application/x-www-form-urlencoded":
+		return analyzer.ParseURLList(strings.NewReader(r.FormValue("urls")), analyzer.FormatText)
+	case mimeJSON:
+		return analyzer.ParseURLList(r.Body, analyzer.FormatJSON)
+	case "text/csv":
+		return analyzer.ParseURLList(r.Body, analyzer.FormatCSV)
+	default:
+		return analyzer.ParseURLList(r.Body, analyzer.FormatText)
+	}
+}
+
+// formatFromFilename guesses the list format of an uploaded file
+func formatFromFilename(name, contentType string) string {
+	switch {
+	case strings.EqualFold(filepath.Ext(name), ".csv") || strings.HasPrefix(contentType, "text/csv"):
+		return analyzer.FormatCSV
+	case strings.EqualFold(filepath.Ext(name), ".json") || strings.HasPrefix(contentType, mimeJSON):
+		return analyzer.FormatJSON
+	default:
+		return analyzer.FormatText
+	}
+}
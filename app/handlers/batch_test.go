@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/checker"
+)
+
+func TestBatchHandler_JSON(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/v1/batch", strings.NewReader(`["http://192.168.1.1", "ftp://example.com"]`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	http.HandlerFunc(newTestHandler(t).BatchHandler).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var report analyzer.BatchReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 2 || report.Summary.Failed != 2 {
+		t.Errorf("unexpected report: %+v", report.Summary)
+	}
+}
+
+func TestBatchHandler_OutlivesWriteTimeout(t *testing.T) {
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte(`<html><head><title>Slow</title></head><body></body></html>`))
+	}))
+	defer page.Close()
+
+	pool := checker.NewPool(2)
+	defer pool.Close()
+	a := analyzer.New(pool, nil, analyzer.Options{
+		Deny:      func(net.IP) bool { return false }, // the test server listens on loopback
+		Transport: http.DefaultTransport,
+	})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(New(a, pool, nil).BatchHandler))
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(`["`+page.URL+`"]`))
+	if err != nil {
+		t.Fatalf("expected the report past the write timeout, got %v", err)
+	}
+	defer resp.Body.Close()
+	var report analyzer.BatchReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("expected the report past the write timeout, got %v", err)
+	}
+	if report.Summary.Failed != 0 || len(report.Results) != 1 || report.Results[0].Title != "Slow" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestBatchHandler_CSVUpload(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "urls.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte("url,owner\nhttp://10.0.0.1,team-a\n"))
+	mw.Close()
+
+	req := httptest.NewRequest("POST", "/api/v1/batch", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rr := httptest.NewRecorder()
+
+	http.HandlerFunc(newTestHandler(t).BatchHandler).ServeHTTP(rr, req)
+
+	var report analyzer.BatchReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 1 || report.Results[0].URL != "http://10.0.0.1" {
+		t.Errorf("expected the CSV url column to be analyzed, got %+v", report.Results)
+	}
+}
+
+func TestBatchHandler_BadRequests(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		body  string
+	}{
+		{"empty list", "", "\n\n"},
+		{"invalid concurrency", "?concurrency=0", "http://10.0.0.1"},
+		{"too much concurrency", "?concurrency=1000", "http://10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/v1/batch"+tt.query, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "text/plain")
+		rr := httptest.NewRecorder()
+
+		http.HandlerFunc(newTestHandler(t).BatchHandler).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", tt.name, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	tests := map[string]string{
+		"urls.csv":  analyzer.FormatCSV,
+		"URLS.JSON": analyzer.FormatJSON,
+		"urls.txt":  analyzer.FormatText,
+		"urls":      analyzer.FormatText,
+	}
+	for name, want := range tests {
+		if got := formatFromFilename(name, ""); got != want {
+			t.Errorf("formatFromFilename(%q) = %s, want %s", name, got, want)
+		}
+	}
+}
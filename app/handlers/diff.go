@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+)
+
+// diffPage is the data of the diff template
+type diffPage struct {
+	Before string
+	After  string
+	Diff   *analyzer.Diff
+	Error  string
+}
+
+// DiffHandler serves GET /diff, comparing two analyses named by the before and after
+// query parameters, answering with JSON when the client asks for it. Without them
+// only the form is shown.
+func (h *Handler) DiffHandler(w http.ResponseWriter, r *http.Request) {
+	if negotiate(r, mimeHTML, mimeJSON) == mimeJSON {
+		h.APIDiffHandler(w, r)
+		return
+	}
+
+	tmpl, err := h.template()
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	page := diffPage{
+		Before: strings.TrimSpace(r.FormValue("before")),
+		After:  strings.TrimSpace(r.FormValue("after")),
+	}
+	status := http.StatusOK
+	if page.Before != "" || page.After != "" {
+		var d analyzer.Diff
+		if d, status, err = h.diff(r.Context(), page.Before, page.After); err != nil {
+			page.Error = err.Error()
+		} else {
+			page.Diff = &d
+		}
+	}
+	w.Header().Set("Content-Type", mimeHTML+"; charset=utf-8")
+	w.WriteHeader(status)
+	_ = render(w, tmpl, "diff.html", page)
+}
+
+// APIDiffHandler serves GET /api/v1/diff?before=<ref>&after=<ref>
+func (h *Handler) APIDiffHandler(w http.ResponseWriter, r *http.Request) {
+	before := strings.TrimSpace(r.FormValue("before"))
+	after := strings.TrimSpace(r.FormValue("after"))
+	d, status, err := h.diff(r.Context(), before, after)
+	if err != nil {
+		writeAPIError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// diff compares the analyses named by before and after, each the ID of a stored
+// analysis or a URL analyzed now. Live analyses run side by side and are saved to
+// the history. On failure it returns the status and message of the error to answer.
+func (h *Handler) diff(ctx context.Context, before, after string) (analyzer.Diff, int, error) {
+	if before == "" || after == "" {
+		return analyzer.Diff{}, http.StatusBadRequest, errors.New("both before and after are required")
+	}
+
+	type resolved struct {
+		analysis analyzer.PageAnalysis
+		status   int
+		err      error
+	}
+	refs := []string{before, after}
+	results := make([]resolved, len(refs))
+	var wg sync.WaitGroup
+	for i, ref := range refs {
+		wg.Add(1)
+		go func(i int, ref string) {
+			defer wg.Done()
+			a, status, err := h.resolve(ctx, ref)
+			results[i] = resolved{a, status, err}
+		}(i, ref)
+	}
+	wg.Wait()
+
+	for i, name := range []string{"before", "after"} {
+		if err := results[i].err; err != nil {
+			return analyzer.Diff{}, results[i].status, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return analyzer.Compare(results[0].analysis, results[1].analysis), http.StatusOK, nil
+}
+
+// resolve returns the analysis named by ref: a URL is analyzed now, anything else
+// is looked up in the history
+func (h *Handler) resolve(ctx context.Context, ref string) (analyzer.PageAnalysis, int, error) {
+	if !strings.Contains(ref, "://") {
+		rec, status, err := h.lookup(ctx, ref)
+		return rec.Analysis, status, err
+	}
+
+	result := h.save(ctx, h.analyzer.AnalyzePage(ctx, ref))
+	// A page that did not answer has nothing to compare
+	if result.Refused() {
+		return analyzer.PageAnalysis{}, httpStatus(result.Error), errors.New(result.Error.Message)
+	}
+	return result, http.StatusOK, nil
+}
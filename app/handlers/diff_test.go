@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+)
+
+func TestDiffHandler_Stored(t *testing.T) {
+	r, records := newHistoryRouter(t)
+	query := "?before=" + records[0].ID + "&after=" + records[1].ID
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/diff"+query, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rr.Code, rr.Body)
+	}
+	var d analyzer.Diff
+	if err := json.NewDecoder(rr.Body).Decode(&d); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Changed || d.Title == nil || d.Title.After != "Page https://blog.example.com/" || d.Before.ID != records[0].ID {
+		t.Errorf("expected the title change between the stored analyses, got %+v", d)
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/diff"+query, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d", rr.Code)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "Page https://example.com/ → Page https://blog.example.com/") ||
+		!strings.Contains(body, `href="/results/`+records[1].ID+`"`) {
+		t.Error("expected the title change rendered with links to both analyses")
+	}
+}
+
+func TestDiffHandler_Errors(t *testing.T) {
+	r, records := newHistoryRouter(t)
+
+	tests := []struct {
+		query  string
+		status int
+		msg    string
+	}{
+		{"?before=" + records[0].ID, http.StatusBadRequest, "both before and after are required"},
+		{"?before=" + records[0].ID + "&after=unknown", http.StatusNotFound, "after: analysis not found"},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/diff"+tt.query, nil))
+		if rr.Code != tt.status || !strings.Contains(rr.Body.String(), tt.msg) {
+			t.Errorf("%q: expected %d %q, got %d %s", tt.query, tt.status, tt.msg, rr.Code, rr.Body)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/diff", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `id="diffForm"`) {
+		t.Errorf("expected the empty form without parameters, got %d", rr.Code)
+	}
+}
+
+func TestDiffHandler_RefusedURL(t *testing.T) {
+	h := newTestHandler(t)
+
+	rr := httptest.NewRecorder()
+	h.APIDiffHandler(rr, httptest.NewRequest("GET", "/api/v1/diff?before=http://192.168.1.1&after=ftp://example.com", nil))
+	if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "before: access to private network denied") {
+		t.Errorf("expected the refused page to be reported, got %d %s", rr.Code, rr.Body)
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"sync"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/checker"
+	"github.com/rabie/page-insight-tool/app/config"
+	"github.com/rabie/page-insight-tool/app/history"
+	"github.com/rabie/page-insight-tool/app/jobs"
+	"github.com/rabie/page-insight-tool/app/monitor"
+)
+
+// Handler serves the web interface and the API on top of the shared services
+type Handler struct {
+	analyzer *analyzer.Analyzer
+	checker  *checker.Pool
+	jobs     *jobs.Manager
+	config   func() *config.Config // running configuration, reported by /readyz
+	history  history.Store         // past analyses, nil when not kept
+	monitors *monitor.Scheduler    // scheduled analyses, nil when not run
+
+	tmplMu sync.Mutex
+	tmpl   *template.Template // index template, parsed on first use
+}
+
+// New returns a Handler using the given services
+func New(a *analyzer.Analyzer, pool *checker.Pool, jobManager *jobs.Manager) *Handler {
+	return &Handler{
+		analyzer: a,
+		checker:  pool,
+		jobs:     jobManager,
+	}
+}
+
+// SetConfig makes /readyz report whether current returns a configuration
+func (h *Handler) SetConfig(current func() *config.Config) {
+	h.config = current
+}
+
+// SetHistory makes the handler save the analyses it runs to store and serve them back
+func (h *Handler) SetHistory(store history.Store) {
+	h.history = store
+}
+
+// SetMonitors makes /api/v1/monitors report the monitors of scheduler
+func (h *Handler) SetMonitors(scheduler *monitor.Scheduler) {
+	h.monitors = scheduler
+}
+
+// CheckerStatsHandler serves GET /api/v1/checker/stats with the occupancy of the link checker pool
+func (h *Handler) CheckerStatsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.checker.Stats())
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rabie/page-insight-tool/app/version"
+)
+
+// saturatedBacklog is the number of link checks queued per worker past which the
+// pool is saturated and the instance reports itself not ready
+const saturatedBacklog = 50
+
+// readiness is the body of /readyz: the overall status and the result of each check
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// HealthHandler serves GET /healthz. It answers as long as the process serves requests.
+func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ReadyHandler serves GET /readyz: 200 when the configuration is loaded, the templates
+// parse and the link checker pool keeps up, 503 naming the failed checks otherwise
+func (h *Handler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{
+		"config":       "ok",
+		"templates":    "ok",
+		"link_checker": "ok",
+	}
+	if h.config == nil || h.config() == nil {
+		checks["config"] = "not loaded"
+	}
+	if _, err := h.template(); err != nil {
+		checks["templates"] = err.Error()
+	}
+	if stats := h.checker.Stats(); stats.Queued > stats.Workers*saturatedBacklog {
+		checks["link_checker"] = fmt.Sprintf("saturated: %d link checks queued for %d workers", stats.Queued, stats.Workers)
+	}
+
+	body := readiness{Status: "ready", Checks: checks}
+	status := http.StatusOK
+	for _, result := range checks {
+		if result != "ok" {
+			body.Status, status = "not_ready", http.StatusServiceUnavailable
+		}
+	}
+	writeJSON(w, status, body)
+}
+
+// VersionHandler serves GET /version with the build of the running binary
+func (h *Handler) VersionHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, version.Get())
+}
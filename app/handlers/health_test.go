@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/checker"
+	"github.com/rabie/page-insight-tool/app/config"
+)
+
+func getReadiness(t *testing.T, h *Handler) (int, readiness) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	h.ReadyHandler(rr, httptest.NewRequest("GET", "/readyz", nil))
+	var body readiness
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return rr.Code, body
+}
+
+func TestHealthHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newTestHandler(t).HealthHandler(rr, httptest.NewRequest("GET", "/healthz", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rr.Code)
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	h := newTestHandler(t)
+
+	// The template path is relative to the repository root, missing from the test directory
+	code, body := getReadiness(t, h)
+	if code != http.StatusServiceUnavailable || body.Status != "not_ready" {
+		t.Errorf("expected not ready, got %d %+v", code, body)
+	}
+	if body.Checks["config"] != "not loaded" || body.Checks["templates"] == "ok" || body.Checks["link_checker"] != "ok" {
+		t.Errorf("unexpected checks %+v", body.Checks)
+	}
+
+	h.SetConfig(func() *config.Config { return &config.Config{} })
+	h.tmpl = template.Must(template.New("index").Parse("ok"))
+	code, body = getReadiness(t, h)
+	if code != http.StatusOK || body.Status != "ready" {
+		t.Errorf("expected ready, got %d %+v", code, body)
+	}
+}
+
+func TestReadyHandler_SaturatedPool(t *testing.T) {
+	pool := checker.NewPool(1)
+	defer pool.Close()
+	h := New(nil, pool, nil)
+	h.SetConfig(func() *config.Config { return &config.Config{} })
+	h.tmpl = template.Must(template.New("index").Parse("ok"))
+
+	release := make(chan struct{})
+	go pool.Run(context.Background(), saturatedBacklog+10, func(i int) { <-release })
+	defer close(release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.Stats().Queued <= saturatedBacklog && time.Now().Before(deadline) {
+		runtime.Gosched()
+	}
+
+	code, body := getReadiness(t, h)
+	if code != http.StatusServiceUnavailable || body.Checks["link_checker"] == "ok" {
+		t.Errorf("expected the saturated pool to fail readiness, got %d %+v", code, body)
+	}
+}
+
+func TestVersionHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newTestHandler(t).VersionHandler(rr, httptest.NewRequest("GET", "/version", nil))
+
+	var info map[string]string
+	if err := json.Unmarshal(rr.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info["version"] != "dev" || info["commit"] != "unknown" || info["go_version"] != runtime.Version() {
+		t.Errorf("unexpected build information %+v", info)
+	}
+}
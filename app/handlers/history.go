@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/history"
+)
+
+// Number of history entries listed by default and at most
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// dateLayout is the format of the from and to dates of the history filter
+const dateLayout = "2006-01-02"
+
+// historyPage is the data of the history template
+type historyPage struct {
+	Host    string
+	From    string
+	To      string
+	Entries []history.Summary
+	Error   string
+}
+
+// historyList is the JSON body of a history listing
+type historyList struct {
+	Entries []history.Summary `json:"entries"`
+}
+
+// save records the analysis in the history, when one is kept, and returns it with its ID
+func (h *Handler) save(ctx context.Context, result analyzer.PageAnalysis) analyzer.PageAnalysis {
+	if h.history == nil {
+		return result
+	}
+	return history.Save(ctx, h.history, result)
+}
+
+// HistoryHandler serves GET /history, the past analyses filtered by host and date,
+// answering with JSON when the client asks for it
+func (h *Handler) HistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if negotiate(r, mimeHTML, mimeJSON) == mimeJSON {
+		h.APIHistoryHandler(w, r)
+		return
+	}
+
+	tmpl, err := h.template()
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	page := historyPage{
+		Host: r.FormValue("host"),
+		From: r.FormValue("from"),
+		To:   r.FormValue("to"),
+	}
+	entries, status, err := h.listHistory(r)
+	if err != nil {
+		page.Error = err.Error()
+	}
+	page.Entries = entries
+	w.Header().Set("Content-Type", mimeHTML+"; charset=utf-8")
+	w.WriteHeader(status)
+	_ = render(w, tmpl, "history.html", page)
+}
+
+// APIHistoryHandler serves GET /api/v1/history
+func (h *Handler) APIHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	entries, status, err := h.listHistory(r)
+	if err != nil {
+		writeAPIError(w, status, err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []history.Summary{}
+	}
+	writeJSON(w, http.StatusOK, historyList{Entries: entries})
+}
+
+// ResultHandler serves GET /results/{id}, the permalink of a stored analysis,
+// answering with JSON when the client asks for it
+func (h *Handler) ResultHandler(w http.ResponseWriter, r *http.Request) {
+	if negotiate(r, mimeHTML, mimeJSON) == mimeJSON {
+		h.APIResultHandler(w, r)
+		return
+	}
+
+	rec, status, err := h.record(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	tmpl, err := h.template()
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	_ = render(w, tmpl, tmpl.Name(), rec.Analysis)
+}
+
+// APIResultHandler serves GET /api/v1/results/{id} with the stored record
+func (h *Handler) APIResultHandler(w http.ResponseWriter, r *http.Request) {
+	rec, status, err := h.record(r)
+	if err != nil {
+		writeAPIError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
+
+// listHistory lists the history entries selected by the query string, or returns
+// the status and message of the error to answer
+func (h *Handler) listHistory(r *http.Request) ([]history.Summary, int, error) {
+	if h.history == nil {
+		return nil, http.StatusNotFound, errors.New("history is not kept")
+	}
+	filter, err := historyFilter(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	entries, err := h.history.List(r.Context(), filter)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("failed to read the history")
+	}
+	return entries, http.StatusOK, nil
+}
+
+// record returns the record named in the path, or the status and message of the error to answer
+func (h *Handler) record(r *http.Request) (history.Record, int, error) {
+	return h.lookup(r.Context(), mux.Vars(r)["id"])
+}
+
+// lookup returns the record with the given ID, or the status and message of the error to answer
+func (h *Handler) lookup(ctx context.Context, id string) (history.Record, int, error) {
+	if h.history == nil {
+		return history.Record{}, http.StatusNotFound, errors.New("history is not kept")
+	}
+	rec, err := h.history.Get(ctx, id)
+	switch {
+	case errors.Is(err, history.ErrNotFound):
+		return history.Record{}, http.StatusNotFound, errors.New("analysis not found")
+	case err != nil:
+		return history.Record{}, http.StatusInternalServerError, errors.New("failed to read the analysis")
+	}
+	return rec, http.StatusOK, nil
+}
+
+// historyFilter reads the host, from, to and limit query parameters. Dates are
+// days in UTC and both ends are included.
+func historyFilter(r *http.Request) (history.Filter, error) {
+	f := history.Filter{
+		Host:  strings.TrimSpace(r.FormValue("host")),
+		Limit: defaultHistoryLimit,
+	}
+	if v := r.FormValue("from"); v != "" {
+		from, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return f, errors.New("from must be a date such as 2024-01-31")
+		}
+		f.From = from
+	}
+	if v := r.FormValue("to"); v != "" {
+		to, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return f, errors.New("to must be a date such as 2024-01-31")
+		}
+		f.To = to.AddDate(0, 0, 1)
+	}
+	if v := r.FormValue("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxHistoryLimit {
+			return f, errors.New("limit must be a number between 1 and " + strconv.Itoa(maxHistoryLimit))
+		}
+		f.Limit = limit
+	}
+	return f, nil
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/history"
+)
+
+// newHistoryRouter serves the history routes of a handler keeping a few analyses
+func newHistoryRouter(t *testing.T) (http.Handler, []history.Record) {
+	store := history.NewMemoryStore(history.Retention{})
+	var records []history.Record
+	for i, url := range []string{"https://example.com/", "https://blog.example.com/", "https://other.org/"} {
+		at := time.Date(2024, 3, 10+i, 12, 0, 0, 0, time.UTC)
+		rec := history.NewRecord(analyzer.PageAnalysis{URL: url, Title: "Page " + url, HTMLVersion: "HTML5"}, at)
+		if err := store.Save(context.Background(), rec); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+
+	h := New(nil, nil, nil)
+	h.SetHistory(store)
+	h.tmpl = template.Must(template.ParseFiles(filepath.Join("..", "templates", "index.html"), filepath.Join("..", "templates", "history.html"), filepath.Join("..", "templates", "diff.html")))
+
+	r := mux.NewRouter()
+	r.HandleFunc("/history", h.HistoryHandler).Methods("GET")
+	r.HandleFunc("/results/{id}", h.ResultHandler).Methods("GET")
+	r.HandleFunc("/api/v1/history", h.APIHistoryHandler).Methods("GET")
+	r.HandleFunc("/api/v1/results/{id}", h.APIResultHandler).Methods("GET")
+	r.HandleFunc("/diff", h.DiffHandler).Methods("GET")
+	r.HandleFunc("/api/v1/diff", h.APIDiffHandler).Methods("GET")
+	return r, records
+}
+
+func TestHistoryHandler_API(t *testing.T) {
+	r, records := newHistoryRouter(t)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{records[2].ID, records[1].ID, records[0].ID}},
+		{"?host=*.example.com", []string{records[1].ID, records[0].ID}},
+		{"?from=2024-03-11&to=2024-03-11", []string{records[1].ID}},
+		{"?limit=1", []string{records[2].ID}},
+		{"?host=nowhere.example", []string{}},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/history"+tt.query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%q: got status %d", tt.query, rr.Code)
+		}
+		var body historyList
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Entries == nil {
+			t.Errorf("%q: expected an entries array", tt.query)
+		}
+		var got []string
+		for _, e := range body.Entries {
+			got = append(got, e.ID)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"?from=yesterday", "?to=2024-13-01", "?limit=0"} {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/history"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
+
+func TestHistoryHandler_HTML(t *testing.T) {
+	r, records := newHistoryRouter(t)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/history?host=other.org", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d", rr.Code)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, `href="/results/`+records[2].ID+`"`) || !strings.Contains(body, `value="other.org"`) {
+		t.Error("expected the filtered entry linked to its permalink and the filter kept in the form")
+	}
+	if strings.Contains(body, records[0].ID) {
+		t.Error("expected the other hosts to be filtered out")
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/history?from=bad", nil))
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "from must be a date") {
+		t.Errorf("expected the invalid date to be reported, got %d", rr.Code)
+	}
+}
+
+func TestResultHandler(t *testing.T) {
+	r, records := newHistoryRouter(t)
+	id := records[0].ID
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/results/"+id, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d", rr.Code)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "Page https://example.com/") || !strings.Contains(body, `href="/results/`+id+`"`) {
+		t.Error("expected the stored analysis rendered with its permalink")
+	}
+
+	req := httptest.NewRequest("GET", "/results/"+id, nil)
+	req.Header.Set("Accept", "application/json")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	var rec history.Record
+	if err := json.NewDecoder(rr.Body).Decode(&rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.ID != id || rec.Host != "example.com" || rec.Analysis.ID != id {
+		t.Errorf("unexpected record %+v", rec)
+	}
+
+	for _, path := range []string{"/results/0123456789abcdef01234567", "/api/v1/results/unknown"} {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, rr.Code)
+		}
+	}
+}
+
+func TestHistoryHandler_NotKept(t *testing.T) {
+	h := New(nil, nil, nil)
+
+	rr := httptest.NewRecorder()
+	h.APIHistoryHandler(rr, httptest.NewRequest("GET", "/api/v1/history", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without a history, got %d", rr.Code)
+	}
+	if got := h.save(context.Background(), analyzer.PageAnalysis{URL: "https://example.com"}); got.ID != "" {
+		t.Errorf("expected nothing to be saved without a history, got ID %q", got.ID)
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rabie/page-insight-tool/app/jobs"
+)
+
+// SubmitJobHandler serves POST /api/v1/jobs: it queues the analysis and answers 202 with the job
+func (h *Handler) SubmitJobHandler(w http.ResponseWriter, r *http.Request) {
+	urlStr, err := requestedURL(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if urlStr == "" {
+		writeAPIError(w, http.StatusBadRequest, "URL is required")
+		return
+	}
+
+	job, err := h.jobs.Submit(r.Context(), urlStr)
+	switch {
+	case errors.Is(err, jobs.ErrQueueFull):
+		w.Header().Set("Retry-After", "5")
+		writeAPIError(w, http.StatusServiceUnavailable, "Too many pending jobs, try again later")
+		return
+	case err != nil:
+		writeAPIError(w, http.StatusServiceUnavailable, "Jobs are not accepted at the moment")
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// JobStatusHandler serves GET /api/v1/jobs/{id}
+func (h *Handler) JobStatusHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.jobs.Get(mux.Vars(r)["id"])
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/jobs"
+)
+
+func newJobsRouter(t *testing.T) http.Handler {
+	manager := jobs.NewManager(jobs.Options{Workers: 1}, func(ctx context.Context, url string) analyzer.PageAnalysis {
+		return analyzer.PageAnalysis{URL: url, Title: "Example"}
+	})
+	t.Cleanup(manager.Close)
+
+	h := New(nil, nil, manager)
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/jobs", h.SubmitJobHandler).Methods("POST")
+	r.HandleFunc("/api/v1/jobs/{id}", h.JobStatusHandler).Methods("GET")
+	return r
+}
+
+func TestJobsHandler_SubmitAndPoll(t *testing.T) {
+	r := newJobsRouter(t)
+
+	req := httptest.NewRequest("POST", "/api/v1/jobs", strings.NewReader(`{"url": "https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusAccepted)
+	}
+	var job jobs.Job
+	if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+	if location := rr.Header().Get("Location"); location != "/api/v1/jobs/"+job.ID {
+		t.Errorf("unexpected Location header %q", location)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for job.Status != jobs.StatusDone && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/jobs/"+job.ID, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job.Status != jobs.StatusDone || job.Result == nil || job.Result.Title != "Example" {
+		t.Errorf("expected finished job with result, got %+v", job)
+	}
+}
+
+func TestJobsHandler_Errors(t *testing.T) {
+	r := newJobsRouter(t)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/jobs", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected %v for missing URL, got %v", http.StatusBadRequest, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/jobs/unknown", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected %v for unknown job, got %v", http.StatusNotFound, rr.Code)
+	}
+}
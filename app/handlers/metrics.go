@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"html/template"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/metrics"
+)
+
+var templateRenderSeconds = metrics.Default.NewHistogram("page_insight_template_render_duration_seconds",
+	"Time spent rendering HTML templates, by template.", metrics.DefaultBuckets, "template")
+
+// MetricsHandler serves GET /metrics in the Prometheus text exposition format
+func (h *Handler) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	metrics.Default.Handler().ServeHTTP(w, r)
+}
+
+// render executes the named template of tmpl into w, recording how long it took
+func render(w io.Writer, tmpl *template.Template, name string, data interface{}) error {
+	start := time.Now()
+	err := tmpl.ExecuteTemplate(w, name, data)
+	templateRenderSeconds.Observe(time.Since(start).Seconds(), name)
+	return err
+}
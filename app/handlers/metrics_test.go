@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	h := newTestHandler(t)
+	h.tmpl = template.Must(template.New("index.html").Parse(`{{define "results"}}{{.URL}}{{end}}ok`))
+
+	if _, err := h.renderResults(analyzer.PageAnalysis{URL: "https://example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	h.MetricsHandler(rr, httptest.NewRequest("GET", "/metrics", nil))
+	body := rr.Body.String()
+	for _, want := range []string{
+		"# TYPE page_insight_analyses_total counter",
+		"# TYPE page_insight_page_fetch_duration_seconds histogram",
+		"# TYPE page_insight_link_checks_total counter",
+		"# TYPE page_insight_ssrf_rejections_total counter",
+		`page_insight_template_render_duration_seconds_count{template="results"}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the metrics", want)
+		}
+	}
+}
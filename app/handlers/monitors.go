@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/rabie/page-insight-tool/app/monitor"
+)
+
+// monitorList is the JSON body of the monitor listing
+type monitorList struct {
+	Monitors []monitor.Status `json:"monitors"`
+}
+
+// MonitorsHandler serves GET /api/v1/monitors, the scheduled monitors with their next
+// run and the outcome of their last one
+func (h *Handler) MonitorsHandler(w http.ResponseWriter, r *http.Request) {
+	list := monitorList{Monitors: []monitor.Status{}}
+	if h.monitors != nil {
+		list.Monitors = h.monitors.Statuses()
+	}
+	writeJSON(w, http.StatusOK, list)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/history"
+	"github.com/rabie/page-insight-tool/app/monitor"
+)
+
+func TestMonitorsHandler(t *testing.T) {
+	h := New(nil, nil, nil)
+
+	get := func() monitorList {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h.MonitorsHandler(rr, httptest.NewRequest("GET", "/api/v1/monitors", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rr.Code)
+		}
+		var body monitorList
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+
+	if body := get(); body.Monitors == nil || len(body.Monitors) != 0 {
+		t.Errorf("expected an empty list without a scheduler, got %+v", body)
+	}
+
+	analyze := func(ctx context.Context, url string) analyzer.PageAnalysis {
+		return analyzer.PageAnalysis{URL: url, Title: "Home"}
+	}
+	scheduler := monitor.NewScheduler(history.NewMemoryStore(history.Retention{}), analyze)
+	if err := scheduler.SetMonitors([]monitor.Monitor{{Name: "home", URL: "https://example.com/", Schedule: "@daily"}}); err != nil {
+		t.Fatal(err)
+	}
+	h.SetMonitors(scheduler)
+
+	body := get()
+	if len(body.Monitors) != 1 || body.Monitors[0].Name != "home" || body.Monitors[0].NextRun.IsZero() || body.Monitors[0].LastRun != nil {
+		t.Errorf("unexpected monitors %+v", body.Monitors)
+	}
+}
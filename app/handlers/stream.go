@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+)
+
+// StreamHandler serves GET /api/v1/analyze/stream as Server-Sent Events.
+// It emits a "progress" event per phase and checked link, then a "result" event
+// with the analysis. With format=html an "html" event carrying the rendered
+// results section is sent just before the result.
+func (h *Handler) StreamHandler(w http.ResponseWriter, r *http.Request) {
+	urlStr := strings.TrimSpace(r.FormValue("url"))
+	if urlStr == "" {
+		writeAPIError(w, http.StatusBadRequest, "URL is required")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	// The stream lasts as long as the analysis, which may outlive the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	events := make(chan analyzer.Progress, 64)
+	results := make(chan analyzer.PageAnalysis, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		result := h.analyzer.AnalyzePageWithProgress(r.Context(), urlStr, func(p analyzer.Progress) {
+			select {
+			case events <- p:
+			case <-done:
+			}
+		})
+		results <- h.save(r.Context(), result)
+	}()
+
+	for {
+		select {
+		case p := <-events:
+			writeEvent(w, "progress", p)
+			flusher.Flush()
+		case result := <-results:
+			// Every progress event was queued before the result, send what is left
+			for len(events) > 0 {
+				writeEvent(w, "progress", <-events)
+			}
+			if r.FormValue("format") == "html" {
+				if fragment, err := h.renderResults(result); err == nil {
+					writeEventData(w, "html", fragment)
+				}
+			}
+			writeEvent(w, "result", result)
+			flusher.Flush()
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// renderResults renders the results section of the index template
+func (h *Handler) renderResults(result analyzer.PageAnalysis) (string, error) {
+	tmpl, err := h.template()
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := render(&buf, tmpl, "results", result); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func writeEvent(w http.ResponseWriter, event string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	writeEventData(w, event, string(data))
+}
+
+// writeEventData writes one SSE event, splitting multi-line data into several data fields
+func writeEventData(w http.ResponseWriter, event, data string) {
+	fmt.Fprintf(w, "event: %s\n", event)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
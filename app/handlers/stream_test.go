@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/helper"
+)
+
+func TestStreamHandler_EmptyURL(t *testing.T) {
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(newTestHandler(t).StreamHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/analyze/stream", nil))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestStreamHandler_Result(t *testing.T) {
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(newTestHandler(t).StreamHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/analyze/stream?url=http://192.168.1.1", nil))
+
+	if ct := rr.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected event stream content type, got %s", ct)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "event: result\ndata: {") {
+		t.Errorf("expected a result event, got %q", body)
+	}
+	if !strings.Contains(body, "access to private network denied") {
+		t.Errorf("expected the analysis error in the result, got %q", body)
+	}
+}
+
+func TestWriteEventData_MultiLine(t *testing.T) {
+	rr := httptest.NewRecorder()
+	writeEventData(rr, "html", "<p>\n  hello\n</p>")
+
+	want := "event: html\ndata: <p>\ndata:   hello\ndata: </p>\n\n"
+	if rr.Body.String() != want {
+		t.Errorf("expected %q, got %q", want, rr.Body.String())
+	}
+}
+
+func TestResultsTemplate_Render(t *testing.T) {
+	tmpl, err := template.ParseFiles(filepath.Join("..", "templates", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := analyzer.PageAnalysis{
+		URL:            "https://example.com",
+		Redirects:      []helper.Hop{{URL: "http://example.com", Status: 301}, {URL: "https://example.com", Status: 200}},
+		RedirectIssues: []helper.RedirectIssue{helper.RedirectTooLong},
+		MaxLinks:       200,
+		LinkClasses:    map[analyzer.LinkClass]int{analyzer.ClassOK: 1, analyzer.ClassClientError: 1},
+		Links: []analyzer.LinkReport{
+			{URL: "https://example.com/a", Accessible: true, Status: 200, Class: analyzer.ClassOK, Method: "GET"},
+			{URL: "https://example.com/b", Status: 404, Class: analyzer.ClassClientError, ErrorKind: helper.ErrorKindClientError,
+				Redirects: []helper.Hop{{URL: "https://example.com/b", Status: 302}, {URL: "https://example.com/c", Status: 404}}},
+		},
+	}
+
+	var out strings.Builder
+	if err := tmpl.ExecuteTemplate(&out, "results", result); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"1 client_error", "too_long", "301 http://example.com", "up to 200 links"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected the results to contain %q", want)
+		}
+	}
+
+	result.Error = analyzer.LinkError{Message: "redirect loop"}
+	if err := tmpl.ExecuteTemplate(&out, "results", result); err != nil {
+		t.Fatal(err)
+	}
+}
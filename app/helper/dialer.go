@@ -0,0 +1,98 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ErrBlockedAddress is returned when a host only resolves to addresses that may not be reached
+var ErrBlockedAddress = errors.New("access to private network denied")
+
+// Resolver looks up the addresses of a host; *net.Resolver implements it
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// SafeDialer opens connections only to addresses allowed by Deny, and to hosts
+// accepted by Policy. It resolves host names itself and connects to the very
+// addresses it checked, so a DNS answer changing between a check and the
+// connection cannot reach a private network.
+type SafeDialer struct {
+	Resolver Resolver          // net.DefaultResolver when nil
+	Deny     func(net.IP) bool // IsPrivateIP when nil
+	Policy   *HostPolicy       // every host accepted when nil
+	Dialer   *net.Dialer       // a dialer with a 30s timeout when nil
+}
+
+// DialContext resolves the host of address and connects to its first allowed address
+func (d *SafeDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []net.IPAddr
+	if ip := net.ParseIP(host); ip != nil {
+		addrs = []net.IPAddr{{IP: ip}}
+	} else {
+		addrs, err = d.resolver().LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !d.Policy.Empty() {
+		ips := make([]net.IP, len(addrs))
+		for i, addr := range addrs {
+			ips[i] = addr.IP
+		}
+		if err := d.Policy.Check(host, ips); err != nil {
+			return nil, err
+		}
+	}
+
+	dialer := d.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	}
+	deny := d.Deny
+	if deny == nil {
+		deny = IsPrivateIP
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		if deny(addr.IP) {
+			lastErr = fmt.Errorf("%w: %s resolves to %s", ErrBlockedAddress, host, addr.IP)
+			continue
+		}
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = &net.DNSError{Err: "no addresses found", Name: host, IsNotFound: true}
+	}
+	return nil, lastErr
+}
+
+func (d *SafeDialer) resolver() Resolver {
+	if d.Resolver != nil {
+		return d.Resolver
+	}
+	return net.DefaultResolver
+}
+
+// NewSafeTransport returns an HTTP transport dialing through d. Proxies are disabled
+// since they would connect on the tool's behalf without the address checks.
+func NewSafeTransport(d *SafeDialer) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = d.DialContext
+	return transport
+}
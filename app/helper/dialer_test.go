@@ -0,0 +1,99 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubResolver answers every lookup from a fixed table
+type stubResolver map[string][]string
+
+func (r stubResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+	return addrs, nil
+}
+
+// denyAllButLoopback treats loopback as public so tests can reach httptest servers
+func denyAllButLoopback(ip net.IP) bool {
+	return !ip.IsLoopback() && IsPrivateIP(ip)
+}
+
+func TestSafeDialer_BlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no request to reach the private server")
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	client := &http.Client{Transport: NewSafeTransport(&SafeDialer{
+		Resolver: stubResolver{"rebind.test": {"127.0.0.1"}},
+	})}
+
+	for _, target := range []string{"http://rebind.test:" + port, server.URL} {
+		_, err := client.Get(target)
+		if !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("%s: expected a blocked address error, got %v", target, err)
+		}
+		if ClassifyError(err) != ErrorKindBlocked {
+			t.Errorf("%s: expected kind %q, got %q", target, ErrorKindBlocked, ClassifyError(err))
+		}
+	}
+}
+
+func TestSafeDialer_DialsCheckedAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	client := &http.Client{Transport: NewSafeTransport(&SafeDialer{
+		Resolver: stubResolver{"mixed.test": {"10.0.0.1", "127.0.0.1"}},
+		Deny:     denyAllButLoopback,
+	})}
+
+	res, err := client.Get("http://mixed.test:" + port)
+	if err != nil {
+		t.Fatalf("expected the allowed address to be dialed, got %v", err)
+	}
+	res.Body.Close()
+}
+
+func TestSafeDialer_BlocksRedirects(t *testing.T) {
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected the redirect to the private server to be refused")
+	}))
+	defer private.Close()
+	_, privatePort, _ := net.SplitHostPort(private.Listener.Addr().String())
+
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://internal.test:"+privatePort, http.StatusFound)
+	}))
+	defer public.Close()
+	_, publicPort, _ := net.SplitHostPort(public.Listener.Addr().String())
+
+	// Both servers listen on loopback: only the host names tell them apart
+	resolver := stubResolver{"public.test": {"127.0.0.1"}, "internal.test": {"127.0.0.2"}}
+	deny := func(ip net.IP) bool { return !ip.Equal(net.ParseIP("127.0.0.1")) }
+	client := &http.Client{Transport: NewSafeTransport(&SafeDialer{Resolver: resolver, Deny: deny})}
+
+	_, err := client.Get("http://public.test:" + publicPort)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("expected the redirect to be blocked, got %v", err)
+	}
+}
+
+func TestNewSafeTransport_NoProxy(t *testing.T) {
+	if transport := NewSafeTransport(&SafeDialer{}); transport.Proxy != nil {
+		t.Error("expected proxies to be disabled")
+	}
+}
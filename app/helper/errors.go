@@ -0,0 +1,109 @@
+package helper
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+)
+
+// ErrorKind classifies why a page or a link could not be retrieved
+type ErrorKind string
+
+const (
+	ErrorKindNone        ErrorKind = ""
+	ErrorKindInvalidURL  ErrorKind = "invalid_url"
+	ErrorKindUnsupported ErrorKind = "unsupported_scheme"
+	ErrorKindBlocked     ErrorKind = "blocked"
+	ErrorKindDNS         ErrorKind = "dns"
+	ErrorKindTimeout     ErrorKind = "timeout"
+	ErrorKindTLS         ErrorKind = "tls"
+	ErrorKindNetwork     ErrorKind = "network"
+	ErrorKindClientError ErrorKind = "client_error"
+	ErrorKindServerError ErrorKind = "server_error"
+	ErrorKindBadStatus   ErrorKind = "unexpected_status"
+	ErrorKindParse       ErrorKind = "parse"
+	ErrorKindCanceled    ErrorKind = "canceled"
+	ErrorKindBotBlocked  ErrorKind = "bot_protection"
+	ErrorKindRedirect    ErrorKind = "redirect"
+	ErrorKindPolicy      ErrorKind = "policy"
+)
+
+var errorKindExplanations = map[ErrorKind]string{
+	ErrorKindInvalidURL:  "The link is not a valid URL.",
+	ErrorKindUnsupported: "The link does not point to an HTTP(S) resource and cannot be checked.",
+	ErrorKindBlocked:     "Access to the link host is not allowed.",
+	ErrorKindDNS:         "The link host name could not be resolved.",
+	ErrorKindTimeout:     "The link host did not answer in time.",
+	ErrorKindTLS:         "A secure connection to the link host could not be established.",
+	ErrorKindNetwork:     "The link host could not be reached.",
+	ErrorKindCanceled:    "The check was abandoned because the analysis was canceled.",
+	ErrorKindBotBlocked:  "The link host answered with a bot protection challenge; the page likely works in a browser.",
+	ErrorKindRedirect:    "The link redirects in a loop or through too many hops.",
+	ErrorKindPolicy:      "The link host is refused by the host policy of this deployment.",
+}
+
+// ExplainErrorKind returns a human readable explanation for failures without an HTTP status
+func ExplainErrorKind(kind ErrorKind) string {
+	return errorKindExplanations[kind]
+}
+
+// ClassifyError maps a transport error to an ErrorKind
+func ClassifyError(err error) ErrorKind {
+	if err == nil {
+		return ErrorKindNone
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return ErrorKindTimeout
+		}
+		return ErrorKindDNS
+	}
+
+	if errors.Is(err, ErrBlockedAddress) {
+		return ErrorKindBlocked
+	}
+	if errors.Is(err, ErrHostPolicy) {
+		return ErrorKindPolicy
+	}
+	if errors.Is(err, ErrRedirectLoop) || errors.Is(err, ErrTooManyRedirects) {
+		return ErrorKindRedirect
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorKindCanceled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorKindTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorKindTimeout
+	}
+
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &recordErr) || errors.As(err, &certErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return ErrorKindTLS
+	}
+
+	return ErrorKindNetwork
+}
+
+// ClassifyStatus maps a non-successful HTTP status code to an ErrorKind
+func ClassifyStatus(code int) ErrorKind {
+	switch {
+	case code >= 500:
+		return ErrorKindServerError
+	case code >= 400:
+		return ErrorKindClientError
+	default:
+		return ErrorKindBadStatus
+	}
+}
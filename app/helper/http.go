@@ -1,5 +1,12 @@
 package helper
 
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
 var statusExplanations = map[int]string{
 	// 1xx - Informational
 	100: "The link host server has received the request headers and the client should proceed to send the request body.",
@@ -65,3 +72,25 @@ func GetExplanation(code int) string {
 	explanation, _ := statusExplanations[code]
 	return explanation
 }
+
+// ParseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	when, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := when.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
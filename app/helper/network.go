@@ -1,82 +1,148 @@
 package helper
 
-import "net"
+import (
+	"fmt"
+	"net"
+	"strings"
+)
 
-// IsPrivateIP checks if an IP address is private
+// reservedNetworks lists the address blocks that must never be reached: private and
+// shared address space, loopback, link-local, documentation, benchmarking, multicast
+// and reserved blocks from the IANA IPv4 and IPv6 special-purpose address registries,
+// plus cloud metadata endpoints outside of them.
+var reservedNetworks = mustParseCIDRs(
+	// IPv4
+	"0.0.0.0/8",          // "this network"
+	"10.0.0.0/8",         // private use
+	"100.64.0.0/10",      // shared address space (CGNAT)
+	"127.0.0.0/8",        // loopback
+	"169.254.0.0/16",     // link-local, including the 169.254.169.254 metadata endpoint
+	"172.16.0.0/12",      // private use
+	"192.0.0.0/24",       // IETF protocol assignments
+	"192.0.2.0/24",       // documentation (TEST-NET-1)
+	"192.88.99.0/24",     // 6to4 relay anycast
+	"192.168.0.0/16",     // private use
+	"198.18.0.0/15",      // benchmarking
+	"198.51.100.0/24",    // documentation (TEST-NET-2)
+	"203.0.113.0/24",     // documentation (TEST-NET-3)
+	"224.0.0.0/4",        // multicast
+	"240.0.0.0/4",        // reserved, including the limited broadcast address
+	"168.63.129.16/32",   // Azure platform metadata
+	"100.100.100.200/32", // Alibaba Cloud metadata (also in CGNAT)
+
+	// IPv6
+	"::/128",         // unspecified
+	"::1/128",        // loopback
+	"::/96",          // IPv4-compatible (deprecated)
+	"64:ff9b:1::/48", // local-use IPv4/IPv6 translation
+	"100::/64",       // discard-only
+	"2001::/23",      // IETF protocol assignments, including Teredo
+	"2001:db8::/32",  // documentation
+	"3fff::/20",      // documentation
+	"5f00::/16",      // segment routing SIDs
+	"fc00::/7",       // unique local, including the fd00:ec2::254 metadata endpoint
+	"fe80::/10",      // link-local
+	"fec0::/10",      // site-local (deprecated)
+	"ff00::/8",       // multicast
+)
+
+// Prefixes of IPv6 blocks carrying an IPv4 address, checked against the IPv4 table
+var (
+	nat64Prefix = mustParseCIDRs("64:ff9b::/96")[0] // IPv4 address in the last 4 bytes
+	sixToFour   = mustParseCIDRs("2002::/16")[0]    // IPv4 address in bytes 2 to 5
+)
+
+// IsPrivateIP checks if an IP address is private or otherwise reserved.
+// IPv6 addresses embedding an IPv4 address (IPv4-mapped, NAT64, 6to4) are
+// judged by the embedded address.
 func IsPrivateIP(ip net.IP) bool {
-	// Handle IPv6 addresses
-	if ip.To4() == nil {
-		// For IPv6, check for private ranges
-		// ::1 is localhost
-		if ip.Equal(net.ParseIP("::1")) {
-			return true
-		}
+	return inNetworks(ip, reservedNetworks)
+}
 
-		// Ensure we have enough bytes before accessing
-		if len(ip) < 2 {
-			return false
-		}
+// IPDenyList blocks the reserved addresses of IsPrivateIP and any extra networks
+// configured by the operator
+type IPDenyList struct {
+	extra []*net.IPNet
+}
 
-		// fc00::/7 is unique local address
-		if ip[0] == 0xfc || ip[0] == 0xfd {
-			return true
+// NewIPDenyList returns a deny list adding the given CIDRs, or single addresses, to the reserved ones
+func NewIPDenyList(networks []string) (*IPDenyList, error) {
+	list := &IPDenyList{}
+	for _, n := range networks {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
 		}
-		// fe80::/10 is link-local
-		if ip[0] == 0xfe && (ip[1]&0xc0) == 0x80 {
-			return true
+		if !strings.Contains(n, "/") {
+			ip := net.ParseIP(n)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid denied network %q", n)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			n = fmt.Sprintf("%s/%d", n, bits)
 		}
-		return false
+		_, network, err := net.ParseCIDR(n)
+		if err != nil {
+			return nil, fmt.Errorf("invalid denied network %q: %w", n, err)
+		}
+		list.extra = append(list.extra, network)
+	}
+	return list, nil
+}
+
+// Denies reports whether ip is reserved or falls in one of the extra networks
+func (l *IPDenyList) Denies(ip net.IP) bool {
+	if IsPrivateIP(ip) {
+		return true
 	}
+	return l != nil && inNetworks(ip, l.extra)
+}
 
-	// Check for private IPv4 ranges
-	privateRanges := []struct {
-		start, end net.IP
-	}{
-		{net.ParseIP("10.0.0.0"), net.ParseIP("10.255.255.255")},
-		{net.ParseIP("172.16.0.0"), net.ParseIP("172.31.255.255")},
-		{net.ParseIP("192.168.0.0"), net.ParseIP("192.168.255.255")},
-		{net.ParseIP("127.0.0.0"), net.ParseIP("127.255.255.255")},
-		{net.ParseIP("169.254.0.0"), net.ParseIP("169.254.255.255")},
-		{net.ParseIP("0.0.0.0"), net.ParseIP("0.255.255.255")},
+// inNetworks reports whether ip, or the IPv4 address it embeds, falls in networks
+func inNetworks(ip net.IP, networks []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	// To4 also unwraps IPv4-mapped addresses (::ffff:a.b.c.d)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else if embedded := embeddedIPv4(ip); embedded != nil && inNetworks(embedded, networks) {
+		return true
 	}
 
-	for _, r := range privateRanges {
-		if InRange(ip, r.start, r.end) {
+	for _, network := range networks {
+		if network.Contains(ip) {
 			return true
 		}
 	}
-
 	return false
 }
 
-// InRange checks if an IP is within a range
-func InRange(ip, start, end net.IP) bool {
-	// Convert all IPs to IPv4 for comparison
-	ip4 := ip.To4()
-	start4 := start.To4()
-	end4 := end.To4()
-
-	// If any of the IPs are not IPv4, we can't compare them
-	if ip4 == nil || start4 == nil || end4 == nil {
-		return false
+// embeddedIPv4 returns the IPv4 address carried by a NAT64 or 6to4 address
+func embeddedIPv4(ip net.IP) net.IP {
+	ip = ip.To16()
+	switch {
+	case ip == nil:
+		return nil
+	case nat64Prefix.Contains(ip):
+		return net.IPv4(ip[12], ip[13], ip[14], ip[15]).To4()
+	case sixToFour.Contains(ip):
+		return net.IPv4(ip[2], ip[3], ip[4], ip[5]).To4()
 	}
-
-	return Bytes2Int(ip4) >= Bytes2Int(start4) && Bytes2Int(ip4) <= Bytes2Int(end4)
+	return nil
 }
 
-// Bytes2Int converts IP bytes to integer for comparison
-func Bytes2Int(ip net.IP) uint32 {
-	// Convert to IPv4 if it's an IPv6 address
-	ip = ip.To4()
-	if ip == nil {
-		// If it's not an IPv4 address, return 0 to avoid panic
-		return 0
-	}
-
-	// Ensure we have exactly 4 bytes before accessing them
-	if len(ip) != 4 {
-		return 0
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
 	}
-
-	return uint32(ip[0])<<24 + uint32(ip[1])<<16 + uint32(ip[2])<<8 + uint32(ip[3])
+	return networks
 }
@@ -36,3 +36,120 @@ func TestIsPrivateIP_PublicRanges(t *testing.T) {
 		}
 	}
 }
+
+func TestIsPrivateIP_ReservedRanges(t *testing.T) {
+	tests := []struct {
+		ip      string
+		private bool
+	}{
+		// IPv4 special-purpose blocks
+		{"0.0.0.0", true},
+		{"0.1.2.3", true},
+		{"10.255.255.255", true},
+		{"100.64.0.1", true},
+		{"100.127.255.254", true},
+		{"100.128.0.1", false},
+		{"127.0.0.1", true},
+		{"169.254.169.254", true},
+		{"172.15.255.255", false},
+		{"172.31.0.1", true},
+		{"192.0.0.8", true},
+		{"192.0.2.1", true},
+		{"192.88.99.1", true},
+		{"192.168.0.1", true},
+		{"198.18.0.1", true},
+		{"198.19.255.255", true},
+		{"198.20.0.1", false},
+		{"198.51.100.7", true},
+		{"203.0.113.9", true},
+		{"224.0.0.1", true},
+		{"239.255.255.250", true},
+		{"240.0.0.1", true},
+		{"255.255.255.255", true},
+
+		// Cloud metadata endpoints
+		{"168.63.129.16", true},
+		{"100.100.100.200", true},
+		{"fd00:ec2::254", true},
+
+		// IPv6 special-purpose blocks
+		{"::", true},
+		{"::1", true},
+		{"::127.0.0.1", true},
+		{"64:ff9b:1::1", true},
+		{"100::1", true},
+		{"2001::1", true},
+		{"2001:db8::1", true},
+		{"3fff::1", true},
+		{"5f00::1", true},
+		{"fc00::1", true},
+		{"fe80::1", true},
+		{"fec0::1", true},
+		{"ff02::1", true},
+
+		// IPv6 addresses embedding an IPv4 address
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:10.0.0.1", true},
+		{"::ffff:8.8.8.8", false},
+		{"64:ff9b::10.0.0.1", true},
+		{"64:ff9b::169.254.169.254", true},
+		{"64:ff9b::8.8.8.8", false},
+		{"2002:7f00:1::", true},
+		{"2002:c0a8:101::1", true},
+		{"2002:808:808::1", false},
+
+		// Public addresses
+		{"8.8.8.8", false},
+		{"93.184.216.34", false},
+		{"2606:4700:4700::1111", false},
+		{"2a00:1450:4001:81b::200e", false},
+	}
+
+	for _, tt := range tests {
+		ip := net.ParseIP(tt.ip)
+		if ip == nil {
+			t.Fatalf("invalid test address %s", tt.ip)
+		}
+		if got := IsPrivateIP(ip); got != tt.private {
+			t.Errorf("IsPrivateIP(%s) = %v, expected %v", tt.ip, got, tt.private)
+		}
+	}
+}
+
+func TestIPDenyList(t *testing.T) {
+	list, err := NewIPDenyList([]string{"203.0.114.0/24", " 8.8.4.4 ", "2606:4700::/32", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip     string
+		denied bool
+	}{
+		{"127.0.0.1", true},
+		{"203.0.114.10", true},
+		{"8.8.4.4", true},
+		{"8.8.8.8", false},
+		{"2606:4700:4700::1111", true},
+		{"64:ff9b::203.0.114.1", true},
+		{"1.1.1.1", false},
+	}
+	for _, tt := range tests {
+		if got := list.Denies(net.ParseIP(tt.ip)); got != tt.denied {
+			t.Errorf("Denies(%s) = %v, expected %v", tt.ip, got, tt.denied)
+		}
+	}
+
+	var none *IPDenyList
+	if !none.Denies(net.ParseIP("10.0.0.1")) || none.Denies(net.ParseIP("8.8.8.8")) {
+		t.Error("expected a nil deny list to block the reserved ranges only")
+	}
+}
+
+func TestNewIPDenyList_Invalid(t *testing.T) {
+	for _, network := range []string{"not-an-ip", "10.0.0.0/33", "1.2.3/24"} {
+		if _, err := NewIPDenyList([]string{network}); err == nil {
+			t.Errorf("expected %q to be rejected", network)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrHostPolicy is returned when a URL targets a host refused by the configured HostPolicy
+var ErrHostPolicy = errors.New("host refused by policy")
+
+// hostRule is one entry of a HostPolicy: a host name pattern or a network
+type hostRule struct {
+	raw     string
+	pattern string     // exact host or "*.domain" wildcard, empty for networks
+	network *net.IPNet // CIDR, or a single address, matched against the host's addresses
+}
+
+// HostPolicy restricts the hosts that may be analyzed. Rules are exact host names,
+// wildcard suffixes such as "*.example.com" (matching example.com and all its
+// subdomains), or CIDRs matched against the addresses a host resolves to.
+// Deny rules always win; when allow rules are set, a host must match one of them.
+type HostPolicy struct {
+	allow []hostRule
+	deny  []hostRule
+}
+
+// NewHostPolicy parses the allow and deny rules. A policy without rules accepts every host.
+func NewHostPolicy(allow, deny []string) (*HostPolicy, error) {
+	policy := &HostPolicy{}
+	var err error
+	if policy.allow, err = parseHostRules(allow); err != nil {
+		return nil, err
+	}
+	if policy.deny, err = parseHostRules(deny); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func parseHostRules(rules []string) ([]hostRule, error) {
+	var parsed []hostRule
+	for _, raw := range rules {
+		rule := strings.ToLower(strings.TrimSpace(raw))
+		switch {
+		case rule == "":
+			continue
+		case strings.Contains(rule, "/"):
+			_, network, err := net.ParseCIDR(rule)
+			if err != nil {
+				return nil, fmt.Errorf("invalid host rule %q: %w", raw, err)
+			}
+			parsed = append(parsed, hostRule{raw: rule, network: network})
+		case net.ParseIP(rule) != nil:
+			ip := net.ParseIP(rule)
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			parsed = append(parsed, hostRule{raw: rule, network: &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}})
+		case strings.Contains(rule, "*") && (!strings.HasPrefix(rule, "*.") || strings.Count(rule, "*") > 1):
+			return nil, fmt.Errorf("invalid host rule %q: wildcards are only allowed as a \"*.\" prefix", raw)
+		default:
+			parsed = append(parsed, hostRule{raw: rule, pattern: strings.TrimSuffix(rule, ".")})
+		}
+	}
+	return parsed, nil
+}
+
+// matches reports whether host, or one of its addresses, falls under the rule
+func (r hostRule) matches(host string, ips []net.IP) bool {
+	if r.network == nil {
+		if strings.HasPrefix(r.pattern, "*.") {
+			domain := r.pattern[2:]
+			return host == domain || strings.HasSuffix(host, "."+domain)
+		}
+		return host == r.pattern
+	}
+	for _, ip := range ips {
+		if r.network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Empty reports whether the policy accepts every host
+func (p *HostPolicy) Empty() bool {
+	return p == nil || (len(p.allow) == 0 && len(p.deny) == 0)
+}
+
+// Check returns an error wrapping ErrHostPolicy when host is refused. ips are the
+// addresses the host resolves to, used by network rules; an address literal host
+// is matched against them on its own. A nil policy accepts every host.
+func (p *HostPolicy) Check(host string, ips []net.IP) error {
+	if p.Empty() {
+		return nil
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	}
+
+	for _, rule := range p.deny {
+		if rule.matches(host, ips) {
+			return fmt.Errorf("%w: %s matches the denied rule %q", ErrHostPolicy, host, rule.raw)
+		}
+	}
+	if len(p.allow) == 0 {
+		return nil
+	}
+	for _, rule := range p.allow {
+		if rule.network == nil && rule.matches(host, nil) {
+			return nil
+		}
+	}
+	// Network rules allow a host only when every address it resolves to is allowed
+	if len(ips) > 0 && allCovered(p.allow, ips) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is not in the allowed hosts", ErrHostPolicy, host)
+}
+
+func allCovered(rules []hostRule, ips []net.IP) bool {
+	for _, ip := range ips {
+		covered := false
+		for _, rule := range rules {
+			if rule.network != nil && rule.network.Contains(ip) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package helper
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestHostPolicy_Check(t *testing.T) {
+	policy, err := NewHostPolicy(
+		[]string{"example.com", "*.example.org", "203.0.113.0/24"},
+		[]string{"secret.example.org", "203.0.113.66"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host    string
+		ips     []string
+		allowed bool
+	}{
+		{"example.com", nil, true},
+		{"EXAMPLE.com.", nil, true},
+		{"www.example.com", nil, false},
+		{"example.org", nil, true},
+		{"docs.example.org", nil, true},
+		{"secret.example.org", nil, false},
+		{"other.test", []string{"203.0.113.10"}, true},
+		{"other.test", []string{"203.0.113.10", "198.51.100.1"}, false},
+		{"other.test", []string{"203.0.113.66"}, false},
+		{"203.0.113.5", nil, true},
+		{"203.0.113.66", nil, false},
+		{"other.test", nil, false},
+	}
+	for _, tt := range tests {
+		var ips []net.IP
+		for _, ip := range tt.ips {
+			ips = append(ips, net.ParseIP(ip))
+		}
+		err := policy.Check(tt.host, ips)
+		if (err == nil) != tt.allowed {
+			t.Errorf("Check(%q, %v) = %v, expected allowed %v", tt.host, tt.ips, err, tt.allowed)
+		}
+		if err != nil && !errors.Is(err, ErrHostPolicy) {
+			t.Errorf("Check(%q): expected a policy error, got %v", tt.host, err)
+		}
+	}
+}
+
+func TestHostPolicy_DenyOnly(t *testing.T) {
+	policy, err := NewHostPolicy(nil, []string{"*.competitor.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policy.Check("example.com", nil); err != nil {
+		t.Errorf("expected hosts out of the deny list to be accepted, got %v", err)
+	}
+	if err := policy.Check("shop.competitor.test", nil); err == nil {
+		t.Error("expected a denied subdomain to be refused")
+	}
+
+	var none *HostPolicy
+	if err := none.Check("anything.test", nil); err != nil || !none.Empty() {
+		t.Errorf("expected a nil policy to accept every host, got %v", err)
+	}
+}
+
+func TestNewHostPolicy_Invalid(t *testing.T) {
+	for _, rule := range []string{"*", "ex*ample.com", "*.*.example.com", "10.0.0.0/33"} {
+		if _, err := NewHostPolicy([]string{rule}, nil); err == nil {
+			t.Errorf("expected rule %q to be rejected", rule)
+		}
+	}
+}
+
+func TestSafeDialer_Policy(t *testing.T) {
+	policy, _ := NewHostPolicy(nil, []string{"denied.test"})
+	client := &http.Client{Transport: NewSafeTransport(&SafeDialer{
+		Resolver: stubResolver{"denied.test": {"93.184.216.34"}},
+		Policy:   policy,
+	})}
+
+	_, err := client.Get("http://denied.test")
+	if !errors.Is(err, ErrHostPolicy) || ClassifyError(err) != ErrorKindPolicy {
+		t.Errorf("expected a policy error, got %v", err)
+	}
+}
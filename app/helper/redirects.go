@@ -0,0 +1,101 @@
+package helper
+
+import (
+	"errors"
+	"net/http"
+)
+
+// MaxRedirectHops is the number of redirects followed before a request is abandoned
+const MaxRedirectHops = 20
+
+var (
+	ErrRedirectLoop      = errors.New("redirect loop")
+	ErrTooManyRedirects  = errors.New("too many redirects")
+	errNoRedirectRequest = errors.New("redirect without a response")
+)
+
+// Hop is one response in a redirect chain
+type Hop struct {
+	URL    string `json:"url"`
+	Status int    `json:"status,omitempty"`
+}
+
+// RedirectIssue flags a suspicious redirect chain
+type RedirectIssue string
+
+const (
+	RedirectLoop      RedirectIssue = "loop"
+	RedirectTooLong   RedirectIssue = "too_long"
+	RedirectDowngrade RedirectIssue = "https_downgrade"
+)
+
+// RedirectTrace records the redirects followed by one request. Use CheckRedirect
+// as the http.Client hook, then Finish with the final response.
+type RedirectTrace struct {
+	Limit  int // chains with more redirects than this are flagged as too long
+	Hops   []Hop
+	Issues []RedirectIssue
+}
+
+// NewRedirectTrace returns a trace flagging chains longer than limit
+func NewRedirectTrace(limit int) *RedirectTrace {
+	return &RedirectTrace{Limit: limit}
+}
+
+// CheckRedirect records the redirect answer that led to req. It stops the request
+// on a loop or after MaxRedirectHops redirects.
+func (t *RedirectTrace) CheckRedirect(req *http.Request, via []*http.Request) error {
+	prev := req.Response
+	if prev == nil {
+		return errNoRedirectRequest
+	}
+	t.Hops = append(t.Hops, Hop{URL: prev.Request.URL.String(), Status: prev.StatusCode})
+
+	if prev.Request.URL.Scheme == "https" && req.URL.Scheme == "http" {
+		t.flag(RedirectDowngrade)
+	}
+	if len(via) > t.Limit {
+		t.flag(RedirectTooLong)
+	}
+
+	next := req.URL.String()
+	for _, r := range via {
+		if r.URL.String() == next {
+			t.Hops = append(t.Hops, Hop{URL: next})
+			t.flag(RedirectLoop)
+			return ErrRedirectLoop
+		}
+	}
+	if len(via) >= MaxRedirectHops {
+		t.Hops = append(t.Hops, Hop{URL: next})
+		return ErrTooManyRedirects
+	}
+	return nil
+}
+
+// Finish records the final response of a redirected request
+func (t *RedirectTrace) Finish(res *http.Response) {
+	if len(t.Hops) > 0 && res != nil && res.Request != nil {
+		t.Hops = append(t.Hops, Hop{URL: res.Request.URL.String(), Status: res.StatusCode})
+	}
+}
+
+// Redirected reports whether the request followed at least one redirect
+func (t *RedirectTrace) Redirected() bool {
+	return len(t.Hops) > 0
+}
+
+// Client returns an HTTP client sending its requests through transport and recording
+// their redirects in t. A nil transport uses http.DefaultTransport.
+func (t *RedirectTrace) Client(transport http.RoundTripper) *http.Client {
+	return &http.Client{Transport: transport, CheckRedirect: t.CheckRedirect}
+}
+
+func (t *RedirectTrace) flag(issue RedirectIssue) {
+	for _, existing := range t.Issues {
+		if existing == issue {
+			return
+		}
+	}
+	t.Issues = append(t.Issues, issue)
+}
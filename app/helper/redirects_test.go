@@ -0,0 +1,132 @@
+package helper
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func traceGet(t *testing.T, trace *RedirectTrace, client *http.Client, url string) error {
+	t.Helper()
+	client.CheckRedirect = trace.CheckRedirect
+	res, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	trace.Finish(res)
+	return nil
+}
+
+func TestRedirectTrace_Chain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	trace := NewRedirectTrace(5)
+	if err := traceGet(t, trace, &http.Client{}, server.URL+"/a"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Hop{
+		{URL: server.URL + "/a", Status: 301},
+		{URL: server.URL + "/b", Status: 302},
+		{URL: server.URL + "/c", Status: 200},
+	}
+	if len(trace.Hops) != len(want) {
+		t.Fatalf("expected hops %v, got %v", want, trace.Hops)
+	}
+	for i := range want {
+		if trace.Hops[i] != want[i] {
+			t.Errorf("hop %d: expected %+v, got %+v", i, want[i], trace.Hops[i])
+		}
+	}
+	if len(trace.Issues) != 0 {
+		t.Errorf("expected no issues, got %v", trace.Issues)
+	}
+}
+
+func TestRedirectTrace_NoRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	trace := NewRedirectTrace(5)
+	if err := traceGet(t, trace, &http.Client{}, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if trace.Redirected() || len(trace.Hops) != 0 {
+		t.Errorf("expected no hops, got %v", trace.Hops)
+	}
+}
+
+func TestRedirectTrace_Loop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/a" {
+			http.Redirect(w, r, "/b", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, "/a", http.StatusFound)
+	}))
+	defer server.Close()
+
+	trace := NewRedirectTrace(5)
+	err := traceGet(t, trace, &http.Client{}, server.URL+"/a")
+	if !errors.Is(err, ErrRedirectLoop) {
+		t.Fatalf("expected a redirect loop error, got %v", err)
+	}
+	if ClassifyError(err) != ErrorKindRedirect {
+		t.Errorf("expected kind %q, got %q", ErrorKindRedirect, ClassifyError(err))
+	}
+	if len(trace.Issues) != 1 || trace.Issues[0] != RedirectLoop {
+		t.Errorf("expected a loop issue, got %v", trace.Issues)
+	}
+	if last := trace.Hops[len(trace.Hops)-1]; last.URL != server.URL+"/a" || last.Status != 0 {
+		t.Errorf("expected the chain to end on the repeated URL, got %+v", last)
+	}
+}
+
+func TestRedirectTrace_TooLong(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.URL.Path) < 5 {
+			http.Redirect(w, r, r.URL.Path+"x", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	trace := NewRedirectTrace(2)
+	if err := traceGet(t, trace, &http.Client{}, server.URL+"/"); err != nil {
+		t.Fatal(err)
+	}
+	if len(trace.Hops) != 5 {
+		t.Errorf("expected 5 hops, got %v", trace.Hops)
+	}
+	if len(trace.Issues) != 1 || trace.Issues[0] != RedirectTooLong {
+		t.Errorf("expected a too long issue, got %v", trace.Issues)
+	}
+}
+
+func TestRedirectTrace_Downgrade(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, plain.URL, http.StatusMovedPermanently)
+	}))
+	defer secure.Close()
+
+	trace := NewRedirectTrace(5)
+	if err := traceGet(t, trace, secure.Client(), secure.URL); err != nil {
+		t.Fatal(err)
+	}
+	if len(trace.Issues) != 1 || trace.Issues[0] != RedirectDowngrade {
+		t.Errorf("expected a downgrade issue, got %v", trace.Issues)
+	}
+}
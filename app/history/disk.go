@@ -0,0 +1,147 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiskStore keeps each record as a JSON file in a directory. The summaries are
+// indexed in memory when the store is opened, so listings do not read the files.
+type DiskStore struct {
+	dir       string
+	retention Retention
+
+	mu    sync.Mutex
+	index index
+}
+
+// OpenDiskStore opens the store kept in dir, creating the directory if needed, and
+// drops the records already past the retention. Unreadable files are logged and skipped.
+func OpenDiskStore(dir string, retention Retention) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	d := &DiskStore{dir: dir, retention: retention}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !validID(id) {
+			continue
+		}
+		rec, err := d.read(id)
+		if err != nil {
+			slog.Warn("skipping unreadable history record", "file", filepath.Join(dir, entry.Name()), "error", err)
+			continue
+		}
+		d.index.add(Summarize(rec))
+	}
+	if _, err := d.Prune(context.Background(), time.Now()); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Save writes a new record and drops the records past the retention
+func (d *DiskStore) Save(ctx context.Context, rec Record) error {
+	if !validID(rec.ID) {
+		return fmt.Errorf("invalid record ID %q", rec.ID)
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	// Written aside then renamed, so a crash never leaves a partial record
+	tmp, err := os.CreateTemp(d.dir, rec.ID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), d.path(rec.ID))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.index.add(Summarize(rec))
+	return d.expire(rec.CreatedAt)
+}
+
+// Get reads the record with the given ID
+func (d *DiskStore) Get(ctx context.Context, id string) (Record, error) {
+	if !validID(id) {
+		return Record{}, ErrNotFound
+	}
+	rec, err := d.read(id)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Record{}, ErrNotFound
+	}
+	return rec, err
+}
+
+// List returns the summaries of the records matching f, most recent first
+func (d *DiskStore) List(ctx context.Context, f Filter) ([]Summary, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.index.list(f), nil
+}
+
+// Prune deletes the records past the retention at now
+func (d *DiskStore) Prune(ctx context.Context, now time.Time) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	before := len(d.index.entries)
+	err := d.expire(now)
+	return before - len(d.index.entries), err
+}
+
+// Close does nothing: every record is on disk once saved
+func (d *DiskStore) Close() error {
+	return nil
+}
+
+func (d *DiskStore) expire(now time.Time) error {
+	var errs []error
+	for _, id := range d.index.expire(d.retention, now) {
+		if err := os.Remove(d.path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d *DiskStore) read(id string) (Record, error) {
+	data, err := os.ReadFile(d.path(id))
+	if err != nil {
+		return Record{}, err
+	}
+	var rec Record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return Record{}, err
+	}
+	return rec, nil
+}
+
+func (d *DiskStore) path(id string) string {
+	return filepath.Join(d.dir, id+".json")
+}
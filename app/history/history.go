@@ -0,0 +1,222 @@
+// Package history keeps past analyses so they can be listed, shared and compared
+package history
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/helper"
+)
+
+// idBytes is the number of random bytes of a record ID
+const idBytes = 12
+
+// ErrNotFound is returned for a record that does not exist or was pruned
+var ErrNotFound = errors.New("analysis not found")
+
+// Record is one stored analysis
+type Record struct {
+	ID        string                `json:"id"`
+	Host      string                `json:"host"`
+	CreatedAt time.Time             `json:"created_at"`
+	Analysis  analyzer.PageAnalysis `json:"analysis"`
+}
+
+// Summary describes a record in listings, without its link report
+type Summary struct {
+	ID                string           `json:"id"`
+	URL               string           `json:"url"`
+	Host              string           `json:"host"`
+	CreatedAt         time.Time        `json:"created_at"`
+	Title             string           `json:"title"`
+	ErrorKind         helper.ErrorKind `json:"error_kind,omitempty"`
+	Error             string           `json:"error,omitempty"`
+	InternalLinks     int              `json:"internal_links"`
+	ExternalLinks     int              `json:"external_links"`
+	InaccessibleLinks int              `json:"inaccessible_links"`
+	HasLoginForm      bool             `json:"has_login_form"`
+}
+
+// Filter selects the records listed. Zero fields select everything.
+type Filter struct {
+	URL   string    // exact URL analyzed
+	Host  string    // exact host, or a "*." wildcard suffix matching the domain and its subdomains
+	From  time.Time // records created at or after
+	To    time.Time // records created before
+	Limit int       // most recent records returned
+}
+
+// Retention bounds what a store keeps. Zero fields keep records forever.
+type Retention struct {
+	MaxAge     time.Duration
+	MaxEntries int
+}
+
+// Store persists analysis records
+type Store interface {
+	// Save stores a new record
+	Save(ctx context.Context, rec Record) error
+	// Get returns the record with the given ID, or ErrNotFound
+	Get(ctx context.Context, id string) (Record, error)
+	// List returns the summaries of the records matching f, most recent first
+	List(ctx context.Context, f Filter) ([]Summary, error)
+	// Prune drops the records past the retention at now and returns how many were dropped
+	Prune(ctx context.Context, now time.Time) (int, error)
+	// Close releases the store
+	Close() error
+}
+
+// NewRecord returns a new record of the analysis, made at now
+func NewRecord(analysis analyzer.PageAnalysis, now time.Time) Record {
+	id := newID()
+	analysis.ID = id
+	return Record{
+		ID:        id,
+		Host:      hostOf(analysis.URL),
+		CreatedAt: now.UTC(),
+		Analysis:  analysis,
+	}
+}
+
+// Save records the analysis in s and returns it with the ID of its record set. When
+// it cannot be stored the failure is logged and the analysis returned as is.
+// Refused analyses are not recorded.
+func Save(ctx context.Context, s Store, analysis analyzer.PageAnalysis) analyzer.PageAnalysis {
+	if analysis.Refused() {
+		return analysis
+	}
+	rec := NewRecord(analysis, time.Now())
+	if err := s.Save(ctx, rec); err != nil {
+		slog.ErrorContext(ctx, "failed to save the analysis to the history", "url", analysis.URL, "error", err)
+		return analysis
+	}
+	return rec.Analysis
+}
+
+// Recorded wraps analyze so that every analysis it runs is saved to s
+func Recorded(s Store, analyze func(ctx context.Context, url string) analyzer.PageAnalysis) func(ctx context.Context, url string) analyzer.PageAnalysis {
+	return func(ctx context.Context, url string) analyzer.PageAnalysis {
+		return Save(ctx, s, analyze(ctx, url))
+	}
+}
+
+// Summarize returns the summary of rec
+func Summarize(rec Record) Summary {
+	a := rec.Analysis
+	return Summary{
+		ID:                rec.ID,
+		URL:               a.URL,
+		Host:              rec.Host,
+		CreatedAt:         rec.CreatedAt,
+		Title:             a.Title,
+		ErrorKind:         a.Error.Kind,
+		Error:             a.Error.Message,
+		InternalLinks:     a.InternalLinks,
+		ExternalLinks:     a.ExternalLinks,
+		InaccessibleLinks: a.InaccessibleLinks,
+		HasLoginForm:      a.HasLoginForm,
+	}
+}
+
+// Match reports whether the summary is selected by the filter, its limit aside
+func (f Filter) Match(s Summary) bool {
+	if f.URL != "" && s.URL != f.URL {
+		return false
+	}
+	if f.Host != "" && !matchHost(f.Host, s.Host) {
+		return false
+	}
+	if !f.From.IsZero() && s.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !s.CreatedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+func matchHost(pattern, host string) bool {
+	pattern = strings.ToLower(pattern)
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return host == suffix || strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+func newID() string {
+	b := make([]byte, idBytes)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validID reports whether id may name a record, which keeps IDs used as file names safe
+func validID(id string) bool {
+	if len(id) != 2*idBytes {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// index holds the summaries of a store, oldest first, and applies filters and retention
+type index struct {
+	entries []Summary
+}
+
+func (x *index) add(s Summary) {
+	// Records are saved in time order but may race; keep the slice sorted
+	i := sort.Search(len(x.entries), func(i int) bool { return x.entries[i].CreatedAt.After(s.CreatedAt) })
+	x.entries = append(x.entries, Summary{})
+	copy(x.entries[i+1:], x.entries[i:])
+	x.entries[i] = s
+}
+
+func (x *index) list(f Filter) []Summary {
+	var out []Summary
+	for i := len(x.entries) - 1; i >= 0; i-- {
+		if !f.Match(x.entries[i]) {
+			continue
+		}
+		out = append(out, x.entries[i])
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	return out
+}
+
+// expire removes the entries past the retention at now and returns their IDs
+func (x *index) expire(r Retention, now time.Time) []string {
+	drop := 0
+	if r.MaxAge > 0 {
+		cutoff := now.Add(-r.MaxAge)
+		for drop < len(x.entries) && x.entries[drop].CreatedAt.Before(cutoff) {
+			drop++
+		}
+	}
+	if r.MaxEntries > 0 && len(x.entries)-drop > r.MaxEntries {
+		drop = len(x.entries) - r.MaxEntries
+	}
+	ids := make([]string, drop)
+	for i := range ids {
+		ids[i] = x.entries[i].ID
+	}
+	x.entries = x.entries[drop:]
+	return ids
+}
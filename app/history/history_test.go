@@ -0,0 +1,184 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/helper"
+)
+
+var day = time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+// stores runs test against a fresh store of each implementation
+func stores(t *testing.T, retention Retention, test func(t *testing.T, s Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStore(retention))
+	})
+	t.Run("disk", func(t *testing.T) {
+		s, err := OpenDiskStore(t.TempDir(), retention)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		test(t, s)
+	})
+}
+
+func save(t *testing.T, s Store, url string, at time.Time) Record {
+	t.Helper()
+	rec := NewRecord(analyzer.PageAnalysis{URL: url, Title: "Title of " + url}, at)
+	if err := s.Save(context.Background(), rec); err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func ids(summaries []Summary) []string {
+	var out []string
+	for _, s := range summaries {
+		out = append(out, s.ID)
+	}
+	return out
+}
+
+func TestStore_SaveGet(t *testing.T) {
+	stores(t, Retention{}, func(t *testing.T, s Store) {
+		rec := save(t, s, "https://Example.com/page", day)
+		if rec.Host != "example.com" || rec.Analysis.ID != rec.ID || !validID(rec.ID) {
+			t.Errorf("unexpected record %+v", rec)
+		}
+
+		got, err := s.Get(context.Background(), rec.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != rec.ID || got.Analysis.Title != "Title of https://Example.com/page" || !got.CreatedAt.Equal(day) {
+			t.Errorf("expected the saved record back, got %+v", got)
+		}
+
+		for _, id := range []string{newID(), "../../etc/passwd", ""} {
+			if _, err := s.Get(context.Background(), id); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound for %q, got %v", id, err)
+			}
+		}
+	})
+}
+
+func TestStore_List(t *testing.T) {
+	stores(t, Retention{}, func(t *testing.T, s Store) {
+		a := save(t, s, "https://example.com/", day)
+		b := save(t, s, "https://blog.example.com/", day.Add(24*time.Hour))
+		c := save(t, s, "https://other.org/", day.Add(48*time.Hour))
+
+		tests := []struct {
+			name   string
+			filter Filter
+			want   []string
+		}{
+			{"all, most recent first", Filter{}, []string{c.ID, b.ID, a.ID}},
+			{"exact url", Filter{URL: "https://blog.example.com/"}, []string{b.ID}},
+			{"exact host", Filter{Host: "example.com"}, []string{a.ID}},
+			{"wildcard host", Filter{Host: "*.Example.com"}, []string{b.ID, a.ID}},
+			{"from", Filter{From: day.Add(time.Hour)}, []string{c.ID, b.ID}},
+			{"to is excluded", Filter{To: b.CreatedAt}, []string{a.ID}},
+			{"limit", Filter{Limit: 1}, []string{c.ID}},
+		}
+		for _, tt := range tests {
+			got, err := s.List(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := ids(got); !slices.Equal(ids, tt.want) {
+				t.Errorf("%s: got %v, want %v", tt.name, ids, tt.want)
+			}
+		}
+	})
+}
+
+func TestStore_Retention(t *testing.T) {
+	stores(t, Retention{MaxAge: 48 * time.Hour, MaxEntries: 2}, func(t *testing.T, s Store) {
+		a := save(t, s, "https://a.example/", day)
+		b := save(t, s, "https://b.example/", day.Add(time.Hour))
+		c := save(t, s, "https://c.example/", day.Add(2*time.Hour))
+
+		if _, err := s.Get(context.Background(), a.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected the oldest record to be dropped past MaxEntries, got %v", err)
+		}
+
+		n, err := s.Prune(context.Background(), day.Add(49*time.Hour+30*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Errorf("expected one record past MaxAge, pruned %d", n)
+		}
+		if _, err := s.Get(context.Background(), b.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected the expired record to be dropped, got %v", err)
+		}
+		if got, _ := s.List(context.Background(), Filter{}); len(got) != 1 || got[0].ID != c.ID {
+			t.Errorf("expected only the recent record to remain, got %v", ids(got))
+		}
+	})
+}
+
+func TestDiskStore_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenDiskStore(dir, Retention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := save(t, s, "https://example.com/", time.Now())
+	s.Close()
+
+	// Stray and corrupt files are skipped
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello"), 0o644)
+	os.WriteFile(filepath.Join(dir, newID()+".json"), []byte("{"), 0o644)
+
+	s, err = OpenDiskStore(dir, Retention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.List(context.Background(), Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != rec.ID || got[0].Title != "Title of https://example.com/" {
+		t.Errorf("expected the saved record to be indexed on reopen, got %+v", got)
+	}
+}
+
+func TestSave(t *testing.T) {
+	s := NewMemoryStore(Retention{})
+
+	saved := Save(context.Background(), s, analyzer.PageAnalysis{URL: "https://example.com"})
+	if saved.ID == "" {
+		t.Fatal("expected the analysis to be given the ID of its record")
+	}
+	if _, err := s.Get(context.Background(), saved.ID); err != nil {
+		t.Errorf("expected the analysis to be stored, got %v", err)
+	}
+
+	refused := Save(context.Background(), s, analyzer.PageAnalysis{
+		URL:   "http://127.0.0.1",
+		Error: analyzer.LinkError{Message: "blocked", Kind: helper.ErrorKindBlocked},
+	})
+	if refused.ID != "" {
+		t.Errorf("expected a refused analysis not to be recorded, got ID %q", refused.ID)
+	}
+
+	analyze := Recorded(s, func(ctx context.Context, url string) analyzer.PageAnalysis {
+		return analyzer.PageAnalysis{URL: url}
+	})
+	if got := analyze(context.Background(), "https://example.org"); got.ID == "" {
+		t.Error("expected Recorded to save every analysis")
+	}
+	if got, _ := s.List(context.Background(), Filter{}); len(got) != 2 {
+		t.Errorf("expected 2 records, got %d", len(got))
+	}
+}
@@ -0,0 +1,70 @@
+package history
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps records in memory. It is lost on restart and serves tests and
+// deployments without a history directory.
+type MemoryStore struct {
+	retention Retention
+
+	mu      sync.Mutex
+	records map[string]Record
+	index   index
+}
+
+// NewMemoryStore returns an empty in-memory store applying the retention
+func NewMemoryStore(retention Retention) *MemoryStore {
+	return &MemoryStore{retention: retention, records: make(map[string]Record)}
+}
+
+// Save stores a new record and drops the records past the retention
+func (m *MemoryStore) Save(ctx context.Context, rec Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[rec.ID] = rec
+	m.index.add(Summarize(rec))
+	m.expire(rec.CreatedAt)
+	return nil
+}
+
+// Get returns the record with the given ID
+func (m *MemoryStore) Get(ctx context.Context, id string) (Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.records[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	return rec, nil
+}
+
+// List returns the summaries of the records matching f, most recent first
+func (m *MemoryStore) List(ctx context.Context, f Filter) ([]Summary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.index.list(f), nil
+}
+
+// Prune drops the records past the retention at now
+func (m *MemoryStore) Prune(ctx context.Context, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.expire(now), nil
+}
+
+// Close does nothing
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+func (m *MemoryStore) expire(now time.Time) int {
+	ids := m.index.expire(m.retention, now)
+	for _, id := range ids {
+		delete(m.records, id)
+	}
+	return len(ids)
+}
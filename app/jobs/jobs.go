@@ -0,0 +1,246 @@
+// Package jobs runs page analyses in the background and keeps their results for polling
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/logging"
+)
+
+// Status is the lifecycle state of a job
+type Status string
+
+const (
+	StatusQueued  Status = "queued"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+var (
+	ErrQueueFull = errors.New("job queue is full")
+	ErrClosed    = errors.New("job manager is shut down")
+)
+
+// Job is a snapshot of one background analysis
+type Job struct {
+	ID         string                 `json:"id"`
+	URL        string                 `json:"url"`
+	Status     Status                 `json:"status"`
+	CreatedAt  time.Time              `json:"created_at"`
+	StartedAt  *time.Time             `json:"started_at,omitempty"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+	Result     *analyzer.PageAnalysis `json:"result,omitempty"`
+	RequestID  string                 `json:"request_id,omitempty"` // of the submitting request, logged with the analysis
+}
+
+// AnalyzeFunc runs the analysis of a single URL
+type AnalyzeFunc func(ctx context.Context, url string) analyzer.PageAnalysis
+
+// Options tune the worker pool and the retention of finished jobs
+type Options struct {
+	Workers   int           // analyses running at the same time
+	QueueSize int           // jobs waiting for a worker before Submit is refused
+	MaxJobs   int           // finished jobs kept in memory
+	TTL       time.Duration // how long a finished job is kept
+}
+
+// DefaultOptions returns the options used when none are configured
+func DefaultOptions() Options {
+	return Options{
+		Workers:   4,
+		QueueSize: 100,
+		MaxJobs:   1000,
+		TTL:       time.Hour,
+	}
+}
+
+// Manager queues jobs, runs them on a fixed set of workers and keeps their results in memory
+type Manager struct {
+	opts    Options
+	analyze AnalyzeFunc
+	queue   chan *Job
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu       sync.Mutex
+	jobs     map[string]*Job
+	finished []string // IDs of finished jobs, oldest first
+	closed   bool
+}
+
+// NewManager starts the workers of a new job manager
+func NewManager(opts Options, analyze AnalyzeFunc) *Manager {
+	defaults := DefaultOptions()
+	if opts.Workers < 1 {
+		opts.Workers = defaults.Workers
+	}
+	if opts.QueueSize < 1 {
+		opts.QueueSize = defaults.QueueSize
+	}
+	if opts.MaxJobs < 1 {
+		opts.MaxJobs = defaults.MaxJobs
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = defaults.TTL
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		opts:    opts,
+		analyze: analyze,
+		queue:   make(chan *Job, opts.QueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		jobs:    make(map[string]*Job),
+	}
+	for i := 0; i < opts.Workers; i++ {
+		m.wg.Add(1)
+		go m.work()
+	}
+	return m
+}
+
+// Submit queues the analysis of a URL and returns the new job. The analysis keeps the
+// request ID of ctx but not its cancellation: it outlives the submitting request.
+func (m *Manager) Submit(ctx context.Context, url string) (Job, error) {
+	job := &Job{
+		ID:        newID(),
+		URL:       url,
+		Status:    StatusQueued,
+		CreatedAt: time.Now(),
+		RequestID: logging.RequestID(ctx),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return Job{}, ErrClosed
+	}
+	select {
+	case m.queue <- job:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	m.jobs[job.ID] = job
+	m.evict(time.Now())
+	return *job, nil
+}
+
+// Get returns a snapshot of the job with the given ID
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.evict(time.Now())
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Close stops accepting jobs, cancels the running analyses and waits for the workers to exit.
+// Jobs still queued finish as failed.
+func (m *Manager) Close() {
+	if m.stop() {
+		m.cancel()
+		m.wg.Wait()
+	}
+}
+
+// Shutdown stops accepting jobs and waits for the queued and running ones to finish.
+// When ctx is done first, the remaining analyses are canceled as by Close and ctx.Err()
+// is returned once the workers have exited.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	if !m.stop() {
+		return nil
+	}
+	defer m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		m.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// stop refuses new jobs and reports whether it was the first call to do so
+func (m *Manager) stop() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return false
+	}
+	m.closed = true
+	close(m.queue)
+	return true
+}
+
+func (m *Manager) work() {
+	defer m.wg.Done()
+
+	for job := range m.queue {
+		started := time.Now()
+		m.mu.Lock()
+		job.Status = StatusRunning
+		job.StartedAt = &started
+		m.mu.Unlock()
+
+		ctx := m.ctx
+		if job.RequestID != "" {
+			ctx = logging.WithRequestID(ctx, job.RequestID)
+		}
+		result := m.analyze(ctx, job.URL)
+
+		finished := time.Now()
+		m.mu.Lock()
+		job.Result = &result
+		job.FinishedAt = &finished
+		job.Status = StatusDone
+		if result.Error.Message != "" {
+			job.Status = StatusFailed
+		}
+		m.finished = append(m.finished, job.ID)
+		m.evict(finished)
+		m.mu.Unlock()
+	}
+}
+
+// evict drops finished jobs past their TTL or beyond the retention limit. Callers hold m.mu.
+func (m *Manager) evict(now time.Time) {
+	drop := 0
+	for drop < len(m.finished) {
+		job := m.jobs[m.finished[drop]]
+		if len(m.finished)-drop <= m.opts.MaxJobs && now.Sub(*job.FinishedAt) < m.opts.TTL {
+			break
+		}
+		delete(m.jobs, job.ID)
+		drop++
+	}
+	m.finished = m.finished[drop:]
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,227 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/logging"
+)
+
+// waitFor polls the job until it reaches the wanted status
+func waitFor(t *testing.T, m *Manager, id string, want Status) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok := m.Get(id)
+		if ok && job.Status == want {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not reach status %s", id, want)
+	return Job{}
+}
+
+func TestManager_Lifecycle(t *testing.T) {
+	release := make(chan struct{})
+	m := NewManager(Options{Workers: 1}, func(ctx context.Context, url string) analyzer.PageAnalysis {
+		<-release
+		if url == "bad" {
+			return analyzer.PageAnalysis{URL: url, Error: analyzer.LinkError{Message: "Invalid URL"}}
+		}
+		return analyzer.PageAnalysis{URL: url, Title: "Example"}
+	})
+	defer m.Close()
+
+	good, err := m.Submit(context.Background(), "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if good.Status != StatusQueued || good.ID == "" {
+		t.Errorf("expected a queued job with an ID, got %+v", good)
+	}
+	bad, err := m.Submit(context.Background(), "bad")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, m, good.ID, StatusRunning)
+	if job, _ := m.Get(bad.ID); job.Status != StatusQueued {
+		t.Errorf("expected second job to wait for the only worker, got %s", job.Status)
+	}
+
+	close(release)
+	done := waitFor(t, m, good.ID, StatusDone)
+	if done.Result == nil || done.Result.Title != "Example" || done.FinishedAt == nil {
+		t.Errorf("expected finished job with result, got %+v", done)
+	}
+	failed := waitFor(t, m, bad.ID, StatusFailed)
+	if failed.Result == nil || failed.Result.Error.Message == "" {
+		t.Errorf("expected failed job to keep its error, got %+v", failed)
+	}
+
+	if _, ok := m.Get("unknown"); ok {
+		t.Error("expected unknown job to be missing")
+	}
+}
+
+func TestManager_QueueFull(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	m := NewManager(Options{Workers: 1, QueueSize: 1}, func(ctx context.Context, url string) analyzer.PageAnalysis {
+		<-release
+		return analyzer.PageAnalysis{URL: url}
+	})
+
+	first, _ := m.Submit(context.Background(), "https://a.example")
+	waitFor(t, m, first.ID, StatusRunning)
+
+	if _, err := m.Submit(context.Background(), "https://b.example"); err != nil {
+		t.Fatalf("expected the queue to take one job, got %v", err)
+	}
+	if _, err := m.Submit(context.Background(), "https://c.example"); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+}
+
+func TestManager_Retention(t *testing.T) {
+	m := NewManager(Options{Workers: 1, MaxJobs: 2}, func(ctx context.Context, url string) analyzer.PageAnalysis {
+		return analyzer.PageAnalysis{URL: url}
+	})
+	defer m.Close()
+
+	var ids []string
+	for _, url := range []string{"https://a.example", "https://b.example", "https://c.example"} {
+		job, err := m.Submit(context.Background(), url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, m, job.ID, StatusDone)
+		ids = append(ids, job.ID)
+	}
+
+	if _, ok := m.Get(ids[0]); ok {
+		t.Error("expected the oldest finished job to be evicted")
+	}
+	for _, id := range ids[1:] {
+		if _, ok := m.Get(id); !ok {
+			t.Errorf("expected job %s to be retained", id)
+		}
+	}
+}
+
+func TestManager_TTL(t *testing.T) {
+	m := NewManager(Options{Workers: 1, TTL: 20 * time.Millisecond}, func(ctx context.Context, url string) analyzer.PageAnalysis {
+		return analyzer.PageAnalysis{URL: url}
+	})
+	defer m.Close()
+
+	job, _ := m.Submit(context.Background(), "https://a.example")
+	waitFor(t, m, job.ID, StatusDone)
+
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := m.Get(job.ID); ok {
+		t.Error("expected expired job to be evicted")
+	}
+}
+
+func TestManager_Closed(t *testing.T) {
+	m := NewManager(Options{}, func(ctx context.Context, url string) analyzer.PageAnalysis {
+		return analyzer.PageAnalysis{URL: url}
+	})
+	m.Close()
+
+	if _, err := m.Submit(context.Background(), "https://a.example"); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestManager_CloseCancelsRunning(t *testing.T) {
+	m := NewManager(Options{Workers: 1}, func(ctx context.Context, url string) analyzer.PageAnalysis {
+		<-ctx.Done()
+		return analyzer.PageAnalysis{URL: url, Error: analyzer.LinkError{Message: ctx.Err().Error()}}
+	})
+
+	job, _ := m.Submit(context.Background(), "https://a.example")
+	waitFor(t, m, job.ID, StatusRunning)
+
+	m.Close()
+	if got, _ := m.Get(job.ID); got.Status != StatusFailed {
+		t.Errorf("expected canceled job to fail, got %s", got.Status)
+	}
+}
+
+func TestManager_ShutdownDrains(t *testing.T) {
+	release := make(chan struct{})
+	m := NewManager(Options{Workers: 1}, func(ctx context.Context, url string) analyzer.PageAnalysis {
+		<-release
+		return analyzer.PageAnalysis{URL: url}
+	})
+
+	running, _ := m.Submit(context.Background(), "https://a.example")
+	queued, _ := m.Submit(context.Background(), "https://b.example")
+	waitFor(t, m, running.ID, StatusRunning)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{running.ID, queued.ID} {
+		if got, _ := m.Get(id); got.Status != StatusDone {
+			t.Errorf("expected job %s to finish before shutdown, got %s", id, got.Status)
+		}
+	}
+	if _, err := m.Submit(context.Background(), "https://c.example"); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed after shutdown, got %v", err)
+	}
+}
+
+func TestManager_ShutdownDeadline(t *testing.T) {
+	m := NewManager(Options{Workers: 1}, func(ctx context.Context, url string) analyzer.PageAnalysis {
+		<-ctx.Done()
+		return analyzer.PageAnalysis{URL: url, Error: analyzer.LinkError{Message: ctx.Err().Error()}}
+	})
+
+	job, _ := m.Submit(context.Background(), "https://a.example")
+	waitFor(t, m, job.ID, StatusRunning)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be reported, got %v", err)
+	}
+	if got, _ := m.Get(job.ID); got.Status != StatusFailed {
+		t.Errorf("expected the job running past the deadline to be canceled, got %s", got.Status)
+	}
+}
+
+func TestManager_RequestID(t *testing.T) {
+	seen := make(chan string, 1)
+	m := NewManager(Options{Workers: 1}, func(ctx context.Context, url string) analyzer.PageAnalysis {
+		seen <- logging.RequestID(ctx)
+		return analyzer.PageAnalysis{URL: url}
+	})
+	defer m.Close()
+
+	ctx, cancel := context.WithCancel(logging.WithRequestID(context.Background(), "req-1"))
+	job, err := m.Submit(ctx, "https://a.example")
+	cancel() // the submitting request is over
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.RequestID != "req-1" {
+		t.Errorf("expected the job to record the request ID, got %q", job.RequestID)
+	}
+	if id := <-seen; id != "req-1" {
+		t.Errorf("expected the analysis to run with the request ID, got %q", id)
+	}
+	if got := waitFor(t, m, job.ID, StatusDone); got.Status != StatusDone {
+		t.Errorf("expected the job to outlive the submitting request, got %s", got.Status)
+	}
+}
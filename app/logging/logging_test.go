@@ -0,0 +1,129 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// capture makes the default logger write JSON lines into the returned buffer until the test ends
+func capture(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(New(&buf, level))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// lines decodes the JSON lines logged into buf
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestNew_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo).With("component", "test")
+
+	logger.InfoContext(WithRequestID(context.Background(), "abc123"), "hello", "duration", 1500*time.Millisecond)
+	logger.DebugContext(context.Background(), "hidden")
+	logger.WarnContext(context.Background(), "no request")
+
+	records := lines(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("expected the debug line to be dropped, got %v", records)
+	}
+	if records[0]["request_id"] != "abc123" || records[0]["component"] != "test" || records[0]["level"] != "INFO" {
+		t.Errorf("expected the request ID on the record, got %v", records[0])
+	}
+	if records[0]["duration"] != 1.5 {
+		t.Errorf("expected the duration in seconds, got %v", records[0]["duration"])
+	}
+	if _, ok := records[1]["request_id"]; ok {
+		t.Errorf("expected no request ID without one in the context, got %v", records[1])
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	buf := capture(t, slog.LevelInfo)
+	var seen string
+	handler := AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	}))
+
+	req := httptest.NewRequest("GET", "/api/v1/analyze?url=https://example.com", nil)
+	req.Header.Set(RequestIDHeader, "client-id.42")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if seen != "client-id.42" || rr.Header().Get(RequestIDHeader) != "client-id.42" {
+		t.Errorf("expected the client request ID to be kept, got %q and %q", seen, rr.Header().Get(RequestIDHeader))
+	}
+	records := lines(t, buf)
+	if len(records) != 1 {
+		t.Fatalf("expected one access log line, got %v", records)
+	}
+	record := records[0]
+	if record["msg"] != "request" || record["status"] != float64(http.StatusTeapot) || record["bytes"] != float64(15) ||
+		record["uri"] != "/api/v1/analyze?url=https://example.com" || record["request_id"] != "client-id.42" {
+		t.Errorf("unexpected access log line %v", record)
+	}
+}
+
+func TestAccessLog_GeneratesRequestID(t *testing.T) {
+	capture(t, slog.LevelInfo)
+	var seen string
+	handler := AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+	}))
+
+	for _, header := range []string{"", "bad id\nwith newline", strings.Repeat("a", 65)} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(RequestIDHeader, header)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if seen == "" || seen == header || rr.Header().Get(RequestIDHeader) != seen {
+			t.Errorf("expected a new request ID for header %q, got %q", header, seen)
+		}
+	}
+}
+
+func TestAccessLog_Streaming(t *testing.T) {
+	capture(t, slog.LevelInfo)
+	handler := AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Error("expected the response writer to keep supporting Flush")
+			return
+		}
+		w.Write([]byte("data"))
+		flusher.Flush()
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	if !rr.Flushed {
+		t.Error("expected the flush to reach the underlying writer")
+	}
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("requests_total", "Requests served.", "code", "path")
+	latency := r.NewHistogram("latency_seconds", "Request latency.", []float64{1, 0.1})
+	r.NewGaugeFunc("workers", "Running workers.", func() float64 { return 3 })
+
+	requests.Inc("200", "/b")
+	requests.Add(2, "200", "/a")
+	requests.Inc("404", `/"quoted"\path`+"\n")
+	latency.Observe(0.05)
+	latency.Observe(0.1)
+	latency.Observe(4)
+
+	var out strings.Builder
+	if _, err := r.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{code="200",path="/a"} 2
+requests_total{code="200",path="/b"} 1
+requests_total{code="404",path="/\"quoted\"\\path\n"} 1
+# HELP latency_seconds Request latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 4.15
+latency_seconds_count 3
+# HELP workers Running workers.
+# TYPE workers gauge
+workers 3
+`
+	if out.String() != want {
+		t.Errorf("unexpected exposition:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("events_total", "Events.").Inc()
+
+	rr := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if rr.Header().Get("Content-Type") != ContentType {
+		t.Errorf("unexpected content type %q", rr.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rr.Body.String(), "events_total 1\n") {
+		t.Errorf("expected the counter in the body, got %q", rr.Body.String())
+	}
+}
+
+func TestRegistry_DuplicateName(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("events_total", "Events.")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a name twice to panic")
+		}
+	}()
+	r.NewCounter("events_total", "Events.")
+}
+
+func TestCounter_WrongLabelCount(t *testing.T) {
+	c := NewRegistry().NewCounter("events_total", "Events.", "kind")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a missing label value to panic")
+		}
+	}()
+	c.Inc()
+}
@@ -0,0 +1,13 @@
+package monitor
+
+import "github.com/rabie/page-insight-tool/app/metrics"
+
+// Monitoring metrics, served on /metrics
+var (
+	monitorRuns = metrics.Default.NewCounter("page_insight_monitor_runs_total",
+		"Scheduled analyses run, by monitor.", "monitor")
+	alertsRaised = metrics.Default.NewCounter("page_insight_alerts_total",
+		"Alerts raised, by monitor and rule.", "monitor", "rule")
+	notifications = metrics.Default.NewCounter("page_insight_alert_notifications_total",
+		"Alert deliveries, by notifier and result.", "notifier", "result")
+)
@@ -0,0 +1,320 @@
+// Package monitor re-analyzes pages on a schedule and raises alerts when they regress
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/cron"
+	"github.com/rabie/page-insight-tool/app/history"
+	"github.com/rabie/page-insight-tool/app/rule"
+)
+
+// Monitor is a page analyzed on a schedule
+type Monitor struct {
+	Name     string
+	URL      string
+	Schedule string
+	Rules    []rule.Rule // every rule when empty
+}
+
+// Status reports a monitor and its last run
+type Status struct {
+	Name           string      `json:"name"`
+	URL            string      `json:"url"`
+	Schedule       string      `json:"schedule"`
+	Rules          []rule.Rule `json:"rules"`
+	Running        bool        `json:"running"`
+	NextRun        time.Time   `json:"next_run"`
+	LastRun        *time.Time  `json:"last_run,omitempty"`
+	LastAnalysisID string      `json:"last_analysis_id,omitempty"`
+	LastAlerts     []Alert     `json:"last_alerts,omitempty"`
+}
+
+// entry is a monitor with its parsed schedule and status
+type entry struct {
+	monitor  Monitor
+	schedule cron.Schedule
+	status   Status
+}
+
+// Scheduler runs the monitors as they fall due. Each run is saved to the history and
+// compared with the previous analyses of the same URL: the ones run by the scheduler,
+// or after a restart the ones saved to the history.
+type Scheduler struct {
+	store   history.Store
+	analyze func(ctx context.Context, url string) analyzer.PageAnalysis
+
+	mu        sync.Mutex
+	monitors  []*entry // in configuration order
+	notifiers []Notifier
+	baselines map[string]Baseline // by URL, including the analyses the history refuses
+	wake      chan struct{}
+	runs      sync.WaitGroup
+}
+
+// NewScheduler returns a scheduler without monitors, analyzing pages with analyze
+// and keeping the results in store
+func NewScheduler(store history.Store, analyze func(ctx context.Context, url string) analyzer.PageAnalysis) *Scheduler {
+	return &Scheduler{
+		store:     store,
+		analyze:   analyze,
+		baselines: make(map[string]Baseline),
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// SetMonitors replaces the monitors, or changes nothing when one is invalid. Monitors
+// kept under the same name keep their status, and their next run when their schedule
+// did not change.
+func (s *Scheduler) SetMonitors(monitors []Monitor) error {
+	now := time.Now()
+	entries := make([]*entry, 0, len(monitors))
+	seen := make(map[string]bool)
+	for _, m := range monitors {
+		if m.Name == "" || seen[m.Name] {
+			return fmt.Errorf("monitor %q: names must be set and unique", m.Name)
+		}
+		seen[m.Name] = true
+		schedule, err := cron.Parse(m.Schedule)
+		if err != nil {
+			return fmt.Errorf("monitor %q: %v", m.Name, err)
+		}
+		if len(m.Rules) == 0 {
+			m.Rules = rule.All
+		}
+		entries = append(entries, &entry{
+			monitor:  m,
+			schedule: schedule,
+			status: Status{
+				Name:     m.Name,
+				URL:      m.URL,
+				Schedule: m.Schedule,
+				Rules:    m.Rules,
+				NextRun:  schedule.Next(now),
+			},
+		})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range entries {
+		for _, old := range s.monitors {
+			if old.monitor.Name != e.monitor.Name {
+				continue
+			}
+			e.status.Running = old.status.Running
+			e.status.LastRun = old.status.LastRun
+			e.status.LastAnalysisID = old.status.LastAnalysisID
+			e.status.LastAlerts = old.status.LastAlerts
+			if old.monitor.Schedule == e.monitor.Schedule {
+				e.status.NextRun = old.status.NextRun
+			}
+		}
+	}
+	for url := range s.baselines {
+		if !slices.ContainsFunc(entries, func(e *entry) bool { return e.monitor.URL == url }) {
+			delete(s.baselines, url)
+		}
+	}
+	s.monitors = entries
+	s.signal()
+	return nil
+}
+
+// SetNotifiers replaces the notifiers the alerts are delivered to
+func (s *Scheduler) SetNotifiers(notifiers ...Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifiers = notifiers
+}
+
+// Statuses returns the status of every monitor, in configuration order
+func (s *Scheduler) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]Status, len(s.monitors))
+	for i, e := range s.monitors {
+		statuses[i] = e.status
+	}
+	return statuses
+}
+
+// Run starts the monitors as they fall due until ctx is done, then waits for the runs
+// in flight, which ctx cancels. A run still going when its monitor falls due again
+// delays the next one until it finishes.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		now := time.Now()
+		var next time.Time
+		s.mu.Lock()
+		for _, e := range s.monitors {
+			if e.status.NextRun.IsZero() {
+				continue
+			}
+			if !e.status.Running && !e.status.NextRun.After(now) {
+				e.status.Running = true
+				e.status.NextRun = e.schedule.Next(now)
+				s.runs.Add(1)
+				go s.run(ctx, e.monitor)
+			}
+			if !e.status.Running && (next.IsZero() || e.status.NextRun.Before(next)) {
+				next = e.status.NextRun
+			}
+		}
+		s.mu.Unlock()
+
+		var timer *time.Timer
+		var due <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(next.Sub(now))
+			due = timer.C
+		}
+		select {
+		case <-due:
+		case <-s.wake:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			s.runs.Wait()
+			return
+		}
+	}
+}
+
+// run runs m and records the outcome in the status of the monitor of the same name,
+// which SetMonitors may have replaced in the meantime
+func (s *Scheduler) run(ctx context.Context, m Monitor) {
+	defer s.runs.Done()
+	id, alerts, err := s.RunNow(ctx, m)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signal()
+	i := slices.IndexFunc(s.monitors, func(e *entry) bool { return e.monitor.Name == m.Name })
+	if i < 0 {
+		return
+	}
+	e := s.monitors[i]
+	e.status.Running = false
+	if err == nil {
+		now := time.Now()
+		e.status.LastRun = &now
+		e.status.LastAnalysisID = id
+		e.status.LastAlerts = alerts
+	}
+}
+
+// RunNow runs the monitor: it analyzes the page, saves the analysis to the history,
+// compares it with the previous analyses of the URL and delivers the alerts raised.
+// It returns the ID of the analysis, empty when the history refused it, and the
+// alerts. A run canceled by ctx raises no alert.
+func (s *Scheduler) RunNow(ctx context.Context, m Monitor) (string, []Alert, error) {
+	if len(m.Rules) == 0 {
+		m.Rules = rule.All
+	}
+	base, err := s.baseline(ctx, m.URL)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to read the previous analyses, comparing with none", "monitor", m.Name, "error", err)
+	}
+
+	result := s.analyze(ctx, m.URL)
+	if ctx.Err() != nil {
+		return "", nil, ctx.Err()
+	}
+	monitorRuns.Inc(m.Name)
+	result = history.Save(ctx, s.store, result)
+	s.mu.Lock()
+	s.baselines[m.URL] = base.next(result)
+	s.mu.Unlock()
+
+	alerts := Check(m.Rules, base, result)
+	now := time.Now().UTC()
+	for i := range alerts {
+		alerts[i].Monitor = m.Name
+		alerts[i].URL = m.URL
+		alerts[i].AnalysisID = result.ID
+		alerts[i].Time = now
+	}
+	s.notify(ctx, alerts)
+	return result.ID, alerts, nil
+}
+
+// baselineSearch bounds the history records read for the last analysis that loaded a page
+const baselineSearch = 100
+
+// baseline returns the analyses of url the next run is compared with
+func (s *Scheduler) baseline(ctx context.Context, url string) (Baseline, error) {
+	s.mu.Lock()
+	base, ok := s.baselines[url]
+	s.mu.Unlock()
+	if ok {
+		return base, nil
+	}
+
+	summaries, err := s.store.List(ctx, history.Filter{URL: url, Limit: baselineSearch})
+	if err != nil || len(summaries) == 0 {
+		return Baseline{}, err
+	}
+	if base.Previous, err = s.get(ctx, summaries[0].ID); err != nil {
+		return Baseline{}, err
+	}
+	for _, summary := range summaries {
+		if summary.Error == "" {
+			base.Loaded, err = s.get(ctx, summary.ID)
+			break
+		}
+	}
+	return base, err
+}
+
+// get returns the analysis of the history record id, nil when it was pruned meanwhile
+func (s *Scheduler) get(ctx context.Context, id string) (*analyzer.PageAnalysis, error) {
+	rec, err := s.store.Get(ctx, id)
+	if errors.Is(err, history.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rec.Analysis, nil
+}
+
+// notify logs the alerts and delivers each to every notifier
+func (s *Scheduler) notify(ctx context.Context, alerts []Alert) {
+	s.mu.Lock()
+	notifiers := s.notifiers
+	s.mu.Unlock()
+
+	for _, alert := range alerts {
+		alertsRaised.Inc(alert.Monitor, string(alert.Rule))
+		slog.WarnContext(ctx, "alert raised", "monitor", alert.Monitor, "url", alert.URL, "rule", alert.Rule,
+			"message", alert.Message, "analysis_id", alert.AnalysisID)
+		for _, n := range notifiers {
+			if err := n.Notify(ctx, alert); err != nil {
+				notifications.Inc(n.Name(), "failed")
+				slog.ErrorContext(ctx, "failed to deliver the alert", "notifier", n.Name(), "monitor", alert.Monitor,
+					"rule", alert.Rule, "error", err)
+				continue
+			}
+			notifications.Inc(n.Name(), "delivered")
+		}
+	}
+}
+
+// signal wakes Run up to look at the monitors again
+func (s *Scheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,246 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/helper"
+	"github.com/rabie/page-insight-tool/app/history"
+	"github.com/rabie/page-insight-tool/app/rule"
+)
+
+// pages serves the analyses of successive runs, repeating the last one
+type pages struct {
+	mu    sync.Mutex
+	runs  []analyzer.PageAnalysis
+	calls int
+}
+
+func (p *pages) analyze(ctx context.Context, url string) analyzer.PageAnalysis {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	a := p.runs[min(p.calls, len(p.runs)-1)]
+	a.URL = url
+	p.calls++
+	return a
+}
+
+// every is a schedule faster than cron.Parse allows
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// failing is a notifier that cannot deliver
+type failing struct{}
+
+func (failing) Name() string                              { return "failing" }
+func (failing) Notify(ctx context.Context, _ Alert) error { return errors.New("unreachable") }
+
+func TestScheduler_RunNow(t *testing.T) {
+	store := history.NewMemoryStore(history.Retention{})
+	p := &pages{runs: []analyzer.PageAnalysis{page("Home"), page("Home", "https://example.com/gone")}}
+	rcv := newReceiver(t, http.StatusOK)
+	s := NewScheduler(store, p.analyze)
+	s.SetNotifiers(failing{}, NewWebhook(rcv.URL, time.Second))
+
+	m := Monitor{Name: "home", URL: "https://example.com/"}
+	first, alerts, err := s.RunNow(context.Background(), m)
+	if err != nil || first == "" || len(alerts) != 0 {
+		t.Fatalf("expected a silent baseline run, got %q %+v %v", first, alerts, err)
+	}
+
+	second, alerts, err := s.RunNow(context.Background(), m)
+	if err != nil || len(alerts) != 1 {
+		t.Fatalf("expected one alert, got %+v %v", alerts, err)
+	}
+	alert := alerts[0]
+	if alert.Monitor != "home" || alert.URL != m.URL || alert.Rule != rule.NewBrokenLinks ||
+		alert.AnalysisID != second || alert.PreviousID != first || alert.Time.IsZero() {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+	// The failing notifier does not keep the webhook from receiving the alert
+	if got := <-rcv.alerts; got.AnalysisID != second {
+		t.Errorf("unexpected alert delivered %+v", got)
+	}
+
+	if summaries, _ := store.List(context.Background(), history.Filter{}); len(summaries) != 2 {
+		t.Errorf("expected both runs in the history, got %d", len(summaries))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := s.RunNow(ctx, m); err == nil {
+		t.Error("expected a canceled run to fail")
+	}
+}
+
+func TestScheduler_RunNow_Restart(t *testing.T) {
+	// A new scheduler takes its baseline from the history
+	store := history.NewMemoryStore(history.Retention{})
+	login := page("Home")
+	login.HasLoginForm = true
+	failing := page("")
+	failing.Error = analyzer.LinkError{Status: 503, Message: "page returned status 503"}
+	m := Monitor{Name: "home", URL: "https://example.com/"}
+
+	p := &pages{runs: []analyzer.PageAnalysis{login, failing}}
+	before := NewScheduler(store, p.analyze)
+	for i := 0; i < 2; i++ {
+		if _, _, err := before.RunNow(context.Background(), m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p = &pages{runs: []analyzer.PageAnalysis{login}}
+	_, alerts, err := NewScheduler(store, p.analyze).RunNow(context.Background(), m)
+	if err != nil || len(alerts) != 0 {
+		t.Errorf("expected the login form seen before the outage to raise nothing, got %+v %v", alerts, err)
+	}
+}
+
+func TestScheduler_RunNow_Refused(t *testing.T) {
+	// Refused analyses are not saved to the history, yet serve as the baseline
+	blocked := page("")
+	blocked.Error = analyzer.LinkError{Kind: helper.ErrorKindBlocked, Message: "access to private network denied"}
+	p := &pages{runs: []analyzer.PageAnalysis{blocked}}
+	s := NewScheduler(history.NewMemoryStore(history.Retention{}), p.analyze)
+
+	m := Monitor{Name: "intranet", URL: "http://10.0.0.1/", Rules: []rule.Rule{rule.Non200}}
+	var raised []Alert
+	for i := 0; i < 3; i++ {
+		id, alerts, err := s.RunNow(context.Background(), m)
+		if err != nil || id != "" {
+			t.Fatalf("unexpected run %q %v", id, err)
+		}
+		raised = append(raised, alerts...)
+	}
+	if len(raised) != 1 || raised[0].Message != "page failed: access to private network denied" {
+		t.Errorf("expected a single alert, got %+v", raised)
+	}
+}
+
+func TestScheduler_ReloadDuringRun(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var calls int32
+	analyze := func(ctx context.Context, url string) analyzer.PageAnalysis {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			started <- struct{}{}
+			<-release
+		}
+		return page("Home")
+	}
+	s := NewScheduler(history.NewMemoryStore(history.Retention{}), analyze)
+	monitors := []Monitor{{Name: "home", URL: "https://example.com/", Schedule: "@every 1h"}}
+	if err := s.SetMonitors(monitors); err != nil {
+		t.Fatal(err)
+	}
+	s.monitors[0].status.NextRun = time.Now()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	<-started
+	if err := s.SetMonitors(monitors); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Statuses()[0].Running {
+		t.Error("expected the reloaded monitor to be reported running")
+	}
+	close(release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status := s.Statuses()[0]
+		if !status.Running && status.LastRun != nil && status.LastAnalysisID != "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the run to be recorded on the reloaded monitor, got %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestScheduler_SetMonitors(t *testing.T) {
+	s := NewScheduler(history.NewMemoryStore(history.Retention{}), (&pages{}).analyze)
+	for _, monitors := range [][]Monitor{
+		{{Name: "", Schedule: "@daily"}},
+		{{Name: "a", Schedule: "@daily"}, {Name: "a", Schedule: "@hourly"}},
+		{{Name: "a", Schedule: "soon"}},
+	} {
+		if err := s.SetMonitors(monitors); err == nil {
+			t.Errorf("expected %+v to be rejected", monitors)
+		}
+	}
+
+	if err := s.SetMonitors([]Monitor{{Name: "a", Schedule: "@daily"}, {Name: "b", Schedule: "@hourly", Rules: []rule.Rule{rule.Non200}}}); err != nil {
+		t.Fatal(err)
+	}
+	before := s.Statuses()
+	if len(before) != 2 || !slices.Equal(before[0].Rules, rule.All) || !slices.Equal(before[1].Rules, []rule.Rule{rule.Non200}) {
+		t.Fatalf("unexpected statuses %+v", before)
+	}
+
+	// The next run is kept while the schedule is unchanged
+	if err := s.SetMonitors([]Monitor{{Name: "b", Schedule: "@hourly"}, {Name: "a", Schedule: "@every 1h"}}); err != nil {
+		t.Fatal(err)
+	}
+	after := s.Statuses()
+	if after[0].Name != "b" || !after[0].NextRun.Equal(before[1].NextRun) || after[1].NextRun.Equal(before[0].NextRun) {
+		t.Errorf("unexpected statuses %+v", after)
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	p := &pages{runs: []analyzer.PageAnalysis{page("Home"), page("Welcome")}}
+	rcv := newReceiver(t, http.StatusOK)
+	s := NewScheduler(history.NewMemoryStore(history.Retention{}), p.analyze)
+	s.SetNotifiers(NewWebhook(rcv.URL, time.Second))
+	if err := s.SetMonitors([]Monitor{{Name: "home", URL: "https://example.com/", Schedule: "@every 1h", Rules: []rule.Rule{rule.TitleChanged}}}); err != nil {
+		t.Fatal(err)
+	}
+	// Faster than schedules allow
+	s.monitors[0].schedule = every(10 * time.Millisecond)
+	s.monitors[0].status.NextRun = time.Now()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx)
+	}()
+
+	select {
+	case alert := <-rcv.alerts:
+		if alert.Rule != rule.TitleChanged || alert.Message != `title changed from "Home" to "Welcome"` {
+			t.Errorf("unexpected alert %+v", alert)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the scheduled runs to raise an alert")
+	}
+	cancel()
+	<-done
+
+	status := s.Statuses()[0]
+	if status.Running || status.LastRun == nil || status.LastAnalysisID == "" {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
@@ -0,0 +1,68 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/version"
+)
+
+// DefaultWebhookTimeout bounds the delivery of one alert to a webhook
+const DefaultWebhookTimeout = 10 * time.Second
+
+// Notifier delivers alerts
+type Notifier interface {
+	// Name identifies the notifier in logs and metrics
+	Name() string
+	// Notify delivers the alert, returning an error when it could not
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Webhook posts each alert as a JSON document to a URL
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook returns a notifier posting to url, each delivery bounded by timeout
+func NewWebhook(url string, timeout time.Duration) *Webhook {
+	if timeout <= 0 {
+		timeout = DefaultWebhookTimeout
+	}
+	return &Webhook{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Name returns "webhook"
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+// Notify posts the alert and fails unless the receiver answers with a 2xx status
+func (w *Webhook) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Page-Insight-Tool/"+version.Version)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/rule"
+)
+
+// receiver is a local webhook endpoint recording the alerts posted to it
+type receiver struct {
+	*httptest.Server
+	alerts chan Alert
+}
+
+func newReceiver(t *testing.T, status int) *receiver {
+	t.Helper()
+	r := &receiver{alerts: make(chan Alert, 16)}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var alert Alert
+		if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" ||
+			!strings.HasPrefix(req.UserAgent(), "Page-Insight-Tool/") {
+			t.Errorf("unexpected request %s %v", req.Method, req.Header)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&alert); err != nil {
+			t.Error(err)
+		}
+		r.alerts <- alert
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func TestWebhook(t *testing.T) {
+	rcv := newReceiver(t, http.StatusNoContent)
+	alert := Alert{
+		Monitor: "home",
+		URL:     "https://example.com/",
+		Rule:    rule.NewBrokenLinks,
+		Message: "1 new broken links",
+		Links:   []string{"https://example.com/gone"},
+		Time:    time.Date(2024, 3, 9, 10, 0, 0, 0, time.UTC),
+	}
+	if err := NewWebhook(rcv.URL, 0).Notify(context.Background(), alert); err != nil {
+		t.Fatal(err)
+	}
+	got := <-rcv.alerts
+	if got.Monitor != "home" || got.Rule != rule.NewBrokenLinks || len(got.Links) != 1 || !got.Time.Equal(alert.Time) {
+		t.Errorf("unexpected alert received %+v", got)
+	}
+}
+
+func TestWebhook_Failure(t *testing.T) {
+	rcv := newReceiver(t, http.StatusInternalServerError)
+	err := NewWebhook(rcv.URL, time.Second).Notify(context.Background(), Alert{Rule: rule.Non200})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("expected the status of the receiver in the error, got %v", err)
+	}
+
+	rcv.Close()
+	if err := NewWebhook(rcv.URL, time.Second).Notify(context.Background(), Alert{Rule: rule.Non200}); err == nil {
+		t.Error("expected an unreachable receiver to fail")
+	}
+}
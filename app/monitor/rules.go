@@ -0,0 +1,99 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/rule"
+)
+
+// Alert is raised when a run of a monitor trips one of its rules
+type Alert struct {
+	Monitor    string    `json:"monitor"`
+	URL        string    `json:"url"`
+	Rule       rule.Rule `json:"rule"`
+	Message    string    `json:"message"`
+	Links      []string  `json:"links,omitempty"` // the newly broken links
+	AnalysisID string    `json:"analysis_id,omitempty"`
+	PreviousID string    `json:"previous_id,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// Baseline holds the analyses a run is compared with
+type Baseline struct {
+	Previous *analyzer.PageAnalysis // of the run before, nil on the first
+	Loaded   *analyzer.PageAnalysis // of the last run that loaded the page, nil when none did
+}
+
+// next returns the baseline of the run after current
+func (b Baseline) next(current analyzer.PageAnalysis) Baseline {
+	b.Previous = &current
+	if loaded(current) {
+		b.Loaded = &current
+	}
+	return b
+}
+
+// loaded reports whether the analysis got the page
+func loaded(a analyzer.PageAnalysis) bool {
+	return a.Error.Message == ""
+}
+
+// Check evaluates the rules against the analysis of a run. Rules fire once, when the
+// page changes. Whether the page fails is compared with the run before, and a page
+// already failing is reported on the first run. The content of the page, its broken
+// links, title and login form, is compared with the last run that loaded it, so an
+// outage raises no content alert; the first load is the baseline for broken links
+// and the title, while a login form is reported on it.
+func Check(rules []rule.Rule, base Baseline, current analyzer.PageAnalysis) []Alert {
+	var content *analyzer.Diff
+	if base.Loaded != nil && loaded(current) {
+		d := analyzer.Compare(*base.Loaded, current)
+		content = &d
+	}
+
+	var alerts []Alert
+	raise := func(r rule.Rule, against *analyzer.PageAnalysis, format string, args ...interface{}) *Alert {
+		alert := Alert{Rule: r, Message: fmt.Sprintf(format, args...)}
+		if against != nil {
+			alert.PreviousID = against.ID
+		}
+		alerts = append(alerts, alert)
+		return &alerts[len(alerts)-1]
+	}
+	for _, r := range rules {
+		switch r {
+		case rule.NewBrokenLinks:
+			if content == nil || len(content.NewlyBroken) == 0 {
+				continue
+			}
+			alert := raise(r, base.Loaded, "%d new broken links", len(content.NewlyBroken))
+			for _, link := range content.NewlyBroken {
+				alert.Links = append(alert.Links, link.URL)
+			}
+		case rule.TitleChanged:
+			if content != nil && content.Title != nil {
+				raise(r, base.Loaded, "title changed from %q to %q", content.Title.Before, content.Title.After)
+			}
+		case rule.Non200:
+			if loaded(current) {
+				continue
+			}
+			if base.Previous != nil && analyzer.Compare(*base.Previous, current).Error == nil {
+				continue
+			}
+			if status := current.Error.Status; status != 0 {
+				raise(r, base.Previous, "page answered %d %s", status, http.StatusText(status))
+			} else {
+				raise(r, base.Previous, "page failed: %s", current.Error.Message)
+			}
+		case rule.LoginForm:
+			if current.HasLoginForm && loaded(current) && (base.Loaded == nil || !base.Loaded.HasLoginForm) {
+				raise(r, base.Loaded, "a login form appeared on the page")
+			}
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/rule"
+)
+
+func page(title string, broken ...string) analyzer.PageAnalysis {
+	a := analyzer.PageAnalysis{URL: "https://example.com/", Title: title}
+	for _, url := range broken {
+		a.Links = append(a.Links, analyzer.LinkReport{URL: url, Internal: true, Class: analyzer.ClassClientError, Status: 404})
+	}
+	return a
+}
+
+func rules(alerts []Alert) []rule.Rule {
+	var out []rule.Rule
+	for _, a := range alerts {
+		out = append(out, a.Rule)
+	}
+	return out
+}
+
+// after returns the baseline of the run following runs
+func after(runs ...analyzer.PageAnalysis) Baseline {
+	var base Baseline
+	for _, run := range runs {
+		base = base.next(run)
+	}
+	return base
+}
+
+func TestCheck(t *testing.T) {
+	home := page("Home")
+	broken := page("Home", "https://example.com/gone")
+	failing := page("")
+	failing.Error = analyzer.LinkError{Status: 503, Message: "page returned status 503"}
+	login := page("Home")
+	login.HasLoginForm = true
+
+	// Each case checks the last run against the ones before it
+	tests := []struct {
+		name string
+		runs []analyzer.PageAnalysis
+		want []rule.Rule
+	}{
+		{"first run is the baseline", []analyzer.PageAnalysis{broken}, nil},
+		{"unchanged", []analyzer.PageAnalysis{broken, broken}, nil},
+		{"new broken link", []analyzer.PageAnalysis{home, broken}, []rule.Rule{rule.NewBrokenLinks}},
+		{"title changed", []analyzer.PageAnalysis{home, page("Welcome")}, []rule.Rule{rule.TitleChanged}},
+		{"failing on the first run", []analyzer.PageAnalysis{failing}, []rule.Rule{rule.Non200}},
+		{"starts failing", []analyzer.PageAnalysis{home, failing}, []rule.Rule{rule.Non200}},
+		{"still failing", []analyzer.PageAnalysis{home, failing, failing}, nil},
+		{"recovers unchanged", []analyzer.PageAnalysis{broken, failing, broken}, nil},
+		{"recovers changed", []analyzer.PageAnalysis{home, failing, broken}, []rule.Rule{rule.NewBrokenLinks}},
+		{"first load after failing", []analyzer.PageAnalysis{failing, broken}, nil},
+		{"login form on the first run", []analyzer.PageAnalysis{login}, []rule.Rule{rule.LoginForm}},
+		{"login form appears", []analyzer.PageAnalysis{home, login}, []rule.Rule{rule.LoginForm}},
+		{"login form still there", []analyzer.PageAnalysis{login, login}, nil},
+		{"login form kept through an outage", []analyzer.PageAnalysis{login, failing, login}, nil},
+		{"login form appears during an outage", []analyzer.PageAnalysis{home, failing, login}, []rule.Rule{rule.LoginForm}},
+	}
+	for _, tt := range tests {
+		last := len(tt.runs) - 1
+		got := rules(Check(rule.All, after(tt.runs[:last]...), tt.runs[last]))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheck_Details(t *testing.T) {
+	home := page("Home", "https://example.com/old")
+	home.ID = "home"
+	failing := page("")
+	failing.ID = "failing"
+	failing.Error = analyzer.LinkError{Status: 503, Message: "page returned status 503"}
+	alerts := Check(rule.All, after(home), failing)
+	if len(alerts) != 1 || alerts[0].Message != "page answered 503 Service Unavailable" || alerts[0].PreviousID != "home" {
+		t.Errorf("unexpected alerts %+v", alerts)
+	}
+
+	// Content is compared with the last run that loaded the page
+	alerts = Check([]rule.Rule{rule.NewBrokenLinks}, after(home, failing), page("Welcome", "https://example.com/a", "https://example.com/b"))
+	if len(alerts) != 1 || alerts[0].Message != "2 new broken links" || alerts[0].PreviousID != "home" ||
+		!slices.Equal(alerts[0].Links, []string{"https://example.com/a", "https://example.com/b"}) {
+		t.Errorf("expected only the selected rule, got %+v", alerts)
+	}
+}
@@ -5,10 +5,11 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/rabie/page-insight-tool/app/handlers"
+	"github.com/rabie/page-insight-tool/app/logging"
 )
 
-// New returns a new router
-func New() http.Handler {
+// New returns a new router. Every request is given an ID and logged once served.
+func New(h *handlers.Handler) http.Handler {
 	r := mux.NewRouter()
 
 	// Serve static files
@@ -16,8 +17,30 @@ func New() http.Handler {
 	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", fs))
 
 	// Route handlers
-	r.HandleFunc("/", handlers.IndexHandler).Methods("GET")
-	r.HandleFunc("/analyze", handlers.AnalyzeHandler).Methods("POST")
+	r.HandleFunc("/", h.IndexHandler).Methods("GET")
+	r.HandleFunc("/analyze", h.AnalyzeHandler).Methods("POST")
+	r.HandleFunc("/history", h.HistoryHandler).Methods("GET")
+	r.HandleFunc("/results/{id}", h.ResultHandler).Methods("GET")
+	r.HandleFunc("/diff", h.DiffHandler).Methods("GET")
 
-	return r
+	// Probes, build information and metrics
+	r.HandleFunc("/healthz", h.HealthHandler).Methods("GET")
+	r.HandleFunc("/readyz", h.ReadyHandler).Methods("GET")
+	r.HandleFunc("/version", h.VersionHandler).Methods("GET")
+	r.HandleFunc("/metrics", h.MetricsHandler).Methods("GET")
+
+	// JSON API
+	r.HandleFunc("/api/v1/analyze", h.APIAnalyzeHandler).Methods("GET", "POST")
+	r.HandleFunc("/api/v1/analyze/stream", h.StreamHandler).Methods("GET")
+	r.HandleFunc("/api/v1/batch", h.BatchHandler).Methods("POST")
+	r.HandleFunc("/api/v1/jobs", h.SubmitJobHandler).Methods("POST")
+	r.HandleFunc("/api/v1/jobs/{id}", h.JobStatusHandler).Methods("GET")
+	r.HandleFunc("/jobs/{id}", h.JobStatusHandler).Methods("GET") // alias of /api/v1/jobs/{id}
+	r.HandleFunc("/api/v1/history", h.APIHistoryHandler).Methods("GET")
+	r.HandleFunc("/api/v1/results/{id}", h.APIResultHandler).Methods("GET")
+	r.HandleFunc("/api/v1/diff", h.APIDiffHandler).Methods("GET")
+	r.HandleFunc("/api/v1/monitors", h.MonitorsHandler).Methods("GET")
+	r.HandleFunc("/api/v1/checker/stats", h.CheckerStatsHandler).Methods("GET")
+
+	return logging.AccessLog(r)
 }
@@ -0,0 +1,57 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rabie/page-insight-tool/app/analyzer"
+	"github.com/rabie/page-insight-tool/app/handlers"
+	"github.com/rabie/page-insight-tool/app/jobs"
+)
+
+func TestNew_JobStatusAlias(t *testing.T) {
+	manager := jobs.NewManager(jobs.Options{Workers: 1}, func(ctx context.Context, url string) analyzer.PageAnalysis {
+		return analyzer.PageAnalysis{URL: url, Title: "Example"}
+	})
+	defer manager.Close()
+	r := New(handlers.New(nil, nil, manager))
+
+	req := httptest.NewRequest("POST", "/api/v1/jobs", strings.NewReader(`{"url": "https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusAccepted)
+	}
+	var job jobs.Job
+	if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for job.Status != jobs.StatusDone && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/jobs/"+job.ID, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job.Status != jobs.StatusDone || job.Result == nil || job.Result.Title != "Example" {
+		t.Errorf("expected the finished job with its result, got %+v", job)
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/jobs/unknown", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected %d for an unknown job, got %d", http.StatusNotFound, rr.Code)
+	}
+}
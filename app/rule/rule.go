@@ -0,0 +1,35 @@
+// Package rule names the conditions that raise an alert when a monitored page trips them
+package rule
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rule names a condition that raises an alert when a monitored page trips it
+type Rule string
+
+// Alert rules
+const (
+	NewBrokenLinks Rule = "new_broken_links" // links broken since the previous run
+	TitleChanged   Rule = "title_changed"    // the title differs from the previous run
+	Non200         Rule = "non_200"          // the page stopped answering 200
+	LoginForm      Rule = "login_form"       // a login form appeared on the page
+)
+
+// All lists every rule, applied to monitors that name none
+var All = []Rule{NewBrokenLinks, TitleChanged, Non200, LoginForm}
+
+// Parse returns the rule with the given name
+func Parse(name string) (Rule, error) {
+	for _, r := range All {
+		if string(r) == name {
+			return r, nil
+		}
+	}
+	names := make([]string, len(All))
+	for i, r := range All {
+		names[i] = string(r)
+	}
+	return "", fmt.Errorf("unknown rule %q, want one of %s", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,12 @@
+package rule
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	if r, err := Parse("title_changed"); err != nil || r != TitleChanged {
+		t.Errorf("got %q, %v", r, err)
+	}
+	if _, err := Parse("slow"); err == nil {
+		t.Error("expected an unknown rule to be rejected")
+	}
+}
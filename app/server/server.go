@@ -0,0 +1,121 @@
+// Package server runs the HTTP server of the Page Insight Tool and shuts it down gracefully
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// cancelGrace is how long the requests canceled at the shutdown deadline get to return
+const cancelGrace = 2 * time.Second
+
+// Options configure the HTTP server
+type Options struct {
+	Addr              string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	ShutdownTimeout   time.Duration // how long requests in flight are waited for on shutdown
+	TLSCertFile       string        // HTTPS is served when both files are set
+	TLSKeyFile        string
+
+	// Drain is called on shutdown, once no new request is accepted, to wait for
+	// background work such as queued jobs. It should return when ctx is done.
+	Drain func(ctx context.Context) error
+}
+
+// Server serves HTTP requests until its context is done
+type Server struct {
+	opts   Options
+	srv    *http.Server
+	cancel context.CancelFunc // cancels the context of every request
+}
+
+// New returns a server for handler
+func New(opts Options, handler http.Handler) *Server {
+	base, cancel := context.WithCancel(context.Background())
+	s := &Server{opts: opts, cancel: cancel}
+	s.srv = &http.Server{
+		Addr:              opts.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		ReadTimeout:       opts.ReadTimeout,
+		WriteTimeout:      opts.WriteTimeout,
+		IdleTimeout:       opts.IdleTimeout,
+		MaxHeaderBytes:    opts.MaxHeaderBytes,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+		BaseContext:       func(net.Listener) context.Context { return base },
+	}
+	return s
+}
+
+// Run listens on the configured address and serves until ctx is done; see Serve
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.opts.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve serves the connections of ln until ctx is done. It then stops accepting
+// connections and waits up to ShutdownTimeout for the requests in flight and the
+// Drain function. Requests still running at the deadline are canceled, which
+// aborts their analyses and outbound link checks.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	defer s.cancel()
+
+	errc := make(chan error, 1)
+	go func() {
+		if s.TLS() {
+			errc <- s.srv.ServeTLS(ln, s.opts.TLSCertFile, s.opts.TLSKeyFile)
+		} else {
+			errc <- s.srv.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, waiting for the requests in flight", "timeout", s.opts.ShutdownTimeout)
+	return s.shutdown()
+}
+
+// TLS reports whether the server serves HTTPS
+func (s *Server) TLS() bool {
+	return s.opts.TLSCertFile != "" && s.opts.TLSKeyFile != ""
+}
+
+func (s *Server) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.ShutdownTimeout)
+	defer cancel()
+	// Past the deadline, the requests still running are canceled
+	stop := context.AfterFunc(ctx, s.cancel)
+	defer stop()
+
+	err := s.srv.Shutdown(ctx)
+	if s.opts.Drain != nil {
+		err = errors.Join(err, s.opts.Drain(ctx))
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	slog.Warn("shutdown deadline reached, canceled the requests still running")
+	grace, cancelGraceCtx := context.WithTimeout(context.Background(), cancelGrace)
+	defer cancelGraceCtx()
+	if s.srv.Shutdown(grace) != nil {
+		s.srv.Close()
+	}
+	return err
+}
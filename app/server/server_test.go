@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// start serves handler on a loopback port and returns its URL and the result of Serve
+func start(t *testing.T, ctx context.Context, opts Options, handler http.Handler) (string, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- New(opts, handler).Serve(ctx, ln) }()
+	return "http://" + ln.Addr().String(), done
+}
+
+func TestServer_DrainsRequestsInFlight(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		io.WriteString(w, "done")
+	})
+	drained := false
+	opts := Options{ShutdownTimeout: 2 * time.Second, Drain: func(ctx context.Context) error {
+		drained = true
+		return nil
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	url, done := start(t, ctx, opts, handler)
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		body <- string(data)
+	}()
+
+	<-started
+	cancel()
+	if got := <-body; got != "done" {
+		t.Errorf("expected the request in flight to complete, got %q", got)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("expected a clean shutdown, got %v", err)
+	}
+	if !drained {
+		t.Error("expected the drain function to be called")
+	}
+
+	if _, err := http.Get(url); err == nil {
+		t.Error("expected new connections to be refused after shutdown")
+	}
+}
+
+func TestServer_CancelsRequestsAtDeadline(t *testing.T) {
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-r.Context().Done():
+			close(canceled)
+		case <-time.After(5 * time.Second):
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	url, done := start(t, ctx, Options{ShutdownTimeout: 50 * time.Millisecond}, handler)
+
+	go func() {
+		if resp, err := http.Get(url); err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-started
+	cancel()
+	select {
+	case <-canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the request to be canceled at the shutdown deadline")
+	}
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be reported, got %v", err)
+	}
+}
+
+func TestServer_Options(t *testing.T) {
+	s := New(Options{Addr: ":0", ReadHeaderTimeout: time.Second, MaxHeaderBytes: 4096}, http.NotFoundHandler())
+	if s.srv.ReadHeaderTimeout != time.Second || s.srv.MaxHeaderBytes != 4096 {
+		t.Errorf("expected the options to be applied, got %+v", s.srv)
+	}
+	if s.TLS() {
+		t.Error("expected HTTP without TLS files")
+	}
+	if !New(Options{TLSCertFile: "cert.pem", TLSKeyFile: "key.pem"}, nil).TLS() {
+		t.Error("expected HTTPS with both TLS files")
+	}
+}
@@ -0,0 +1,26 @@
+// Package version reports the build of the running binary. Version and Commit
+// are set at link time by the Makefile:
+//
+//	go build -ldflags "-X github.com/rabie/page-insight-tool/app/version.Version=v1.2.0 \
+//		-X github.com/rabie/page-insight-tool/app/version.Commit=abc1234"
+package version
+
+import "runtime"
+
+// Set with -ldflags -X; left as is by a plain go build
+var (
+	Version = "dev"
+	Commit  = "unknown"
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information of the running binary
+func Get() Info {
+	return Info{Version: Version, Commit: Commit, GoVersion: runtime.Version()}
+}